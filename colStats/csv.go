@@ -4,7 +4,9 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // statsFunc defines a generic statistical function
@@ -25,6 +27,10 @@ func avg (data []float64) float64 {
 }
 
 func min (data []float64) float64 {
+	if len(data) == 0 {
+		return math.NaN()
+	}
+
 	var min float64 = data[0]
 
 	for _, num := range data[1:] {
@@ -37,6 +43,10 @@ func min (data []float64) float64 {
 }
 
 func max (data []float64) float64 {
+	if len(data) == 0 {
+		return math.NaN()
+	}
+
 	var max float64 = data[0]
 
 	for _, num := range data[1:] {
@@ -48,16 +58,99 @@ func max (data []float64) float64 {
 	return max
 }
 
-func csv2float(r io.Reader, column int) ([]float64, error) {
+// colSpec selects a CSV column by its 1-based number or its header name
+type colSpec struct {
+	label string // the column as given by the user
+	name  string // header name, empty if selected by number
+	index int    // 1-based column number
+}
+
+// parseCols parses a comma separated list of columns. Numbers select
+// columns by position and anything else by header name
+func parseCols(cols string) ([]colSpec, error) {
+	var specs []colSpec
+
+	for _, c := range strings.Split(cols, ",") {
+		c = strings.TrimSpace(c)
+
+		n, err := strconv.Atoi(c)
+		switch {
+		case c == "":
+			return nil, fmt.Errorf("%w: empty column", ErrInvalidColumn)
+		case err != nil:
+			specs = append(specs, colSpec{label: c, name: c})
+		case n < 1:
+			return nil, fmt.Errorf("%w: %d", ErrInvalidColumn, n)
+		default:
+			specs = append(specs, colSpec{label: c, index: n})
+		}
+	}
+
+	return specs, nil
+}
+
+// resolveCols returns the 0-based index of each column in cols, looking up
+// columns selected by name in header, which is nil if there's no header
+func resolveCols(cols []colSpec, header []string) ([]int, error) {
+	idx := make([]int, len(cols))
+
+	for i, c := range cols {
+		if c.name == "" {
+			idx[i] = c.index - 1
+			continue
+		}
+
+		idx[i] = -1
+		for j, h := range header {
+			if strings.TrimSpace(h) == c.name {
+				idx[i] = j
+				break
+			}
+		}
+
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("%w: no column named %q", ErrInvalidColumn, c.name)
+		}
+	}
+
+	return idx, nil
+}
+
+// csv2groups reads the values of each column in cols, returning them in the
+// same order, split by the text in the key column. Without a key column,
+// all values are in the "" group. If header is set, the first row holds
+// the column names, so each file can have the columns in a different order
+func csv2groups(r io.Reader, cols []colSpec, key *colSpec, header bool) (map[string][][]float64, error) {
 	// create the CSV Reader used to read in data from CSV files
 	cr := csv.NewReader(r)
 	//  reuse the same slice for each read operation to reduce the memory allocation
 	cr.ReuseRecord = true
 
-	// adjusting for zero based index
-	column--
+	// the key column is resolved along with the others, as the last one
+	specs := cols
+	if key != nil {
+		specs = append(cols[:len(cols):len(cols)], *key)
+	}
+
+	var (
+		idx []int
+		err error
+	)
+
+	if !header {
+		if idx, err = resolveCols(specs, nil); err != nil {
+			return nil, err
+		}
+	}
 
-	var data []float64
+	groups := map[string][][]float64{}
+
+	// without a key column every row goes to the same group, so there's
+	// no need to look it up for each of them
+	data := make([][]float64, len(cols))
+	if key == nil {
+		groups[""] = data
+	}
 
 	// looping through all records
 	for i := 0; ; i++ {
@@ -72,26 +165,44 @@ func csv2float(r io.Reader, column int) ([]float64, error) {
 			return nil, fmt.Errorf("Cannot read data from file: %w", err)
 		}
 
-		// skip the header of csv file
-		if i == 0 {
+		// find the columns in the header of csv file, and skip it
+		if i == 0 && header {
+			if idx, err = resolveCols(specs, row); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
-		// checking number of columns in csv file
-		if len(row) <= column {
-			// file does not have that many columns
-			return nil, fmt.Errorf("%w: File has only %d columns", ErrInvalidColumn, len(row))
+		if key != nil {
+			k := idx[len(cols)]
+			if len(row) <= k {
+				return nil, fmt.Errorf("%w: File has only %d columns", ErrInvalidColumn, len(row))
+			}
+
+			var ok bool
+			if data, ok = groups[row[k]]; !ok {
+				data = make([][]float64, len(cols))
+				groups[row[k]] = data
+			}
 		}
 
-		// try to convert data read into a float number
-		v, err := strconv.ParseFloat(row[column], 64)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %s", ErrNotNumber, err)
-		}
+		for j, column := range idx[:len(cols)] {
+			// checking number of columns in csv file
+			if len(row) <= column {
+				// file does not have that many columns
+				return nil, fmt.Errorf("%w: File has only %d columns", ErrInvalidColumn, len(row))
+			}
 
-		data = append(data, v)
+			// try to convert data read into a float number
+			v, err := strconv.ParseFloat(row[column], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrNotNumber, err)
+			}
+
+			data[j] = append(data[j], v)
+		}
 	}
 
-	// return the slice of float64 and nil error
-	return data, nil
+	// return the groups of float64 slices and nil error
+	return groups, nil
 }
\ No newline at end of file
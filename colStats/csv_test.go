@@ -67,7 +67,7 @@ func TestCSV2Float(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			res, err := csv2float(tc.r, tc.col)
+			groups, err := csv2groups(tc.r, []colSpec{{index: tc.col}}, nil, true)
 
 			// check for erros if expErr is not nil (errors are expected)
 			// two cases: either nil or some other error
@@ -89,6 +89,8 @@ func TestCSV2Float(t *testing.T) {
 				t.Errorf("Unexpected error: %q", err)
 			}
 
+			res := groups[""][0]
+
 			// reflect.DeepEqual can be used here
 			for i, exp := range tc.exp {
 				if res[i] != exp {
@@ -97,4 +99,108 @@ func TestCSV2Float(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParseCols(t *testing.T) {
+	testCases := []struct {
+		name   string
+		cols   string
+		exp    []colSpec
+		expErr error
+	}{
+		{name: "Number", cols: "2", exp: []colSpec{{label: "2", index: 2}}},
+		{name: "Name", cols: "latency_ms", exp: []colSpec{{label: "latency_ms", name: "latency_ms"}}},
+		{name: "Multiple", cols: "1, latency_ms", exp: []colSpec{{label: "1", index: 1}, {label: "latency_ms", name: "latency_ms"}}},
+		{name: "Zero", cols: "0", expErr: ErrInvalidColumn},
+		{name: "Empty", cols: "1,", expErr: ErrInvalidColumn},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseCols(tc.cols)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != len(tc.exp) {
+				t.Fatalf("Expected %v, got %v instead", tc.exp, res)
+			}
+
+			for i := range tc.exp {
+				if res[i] != tc.exp[i] {
+					t.Errorf("Expected %v, got %v instead", tc.exp, res)
+				}
+			}
+		})
+	}
+}
+
+func TestCSV2GroupsColumns(t *testing.T) {
+	csvData := "Requests,Response Time\n2056,236\n899,220\n"
+
+	testCases := []struct {
+		name   string
+		cols   []colSpec
+		header bool
+		input  string
+		exp    [][]float64
+		expErr error
+	}{
+		{name: "ByName", cols: []colSpec{{name: "Response Time"}, {name: "Requests"}}, header: true, input: csvData,
+			exp: [][]float64{{236, 220}, {2056, 899}}},
+		{name: "NoHeader", cols: []colSpec{{index: 2}}, header: false, input: "1,2\n3,4\n",
+			exp: [][]float64{{2, 4}}},
+		{name: "FailUnknownName", cols: []colSpec{{name: "Latency"}}, header: true, input: csvData,
+			expErr: ErrInvalidColumn},
+		{name: "FailNoHeaderName", cols: []colSpec{{name: "Requests"}}, header: false, input: csvData,
+			expErr: ErrInvalidColumn},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups, err := csv2groups(bytes.NewBufferString(tc.input), tc.cols, nil, tc.header)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res := groups[""]; fmt.Sprint(res) != fmt.Sprint(tc.exp) {
+				t.Errorf("Expected %v, got %v instead", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestCSV2Groups(t *testing.T) {
+	csvData := "region,latency\neu,10\nus,30\neu,20\n"
+
+	res, err := csv2groups(bytes.NewBufferString(csvData), []colSpec{{name: "latency"}}, &colSpec{name: "region"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string][][]float64{
+		"eu": {{10, 20}},
+		"us": {{30}},
+	}
+
+	if fmt.Sprint(res) != fmt.Sprint(exp) {
+		t.Errorf("Expected %v, got %v instead", exp, res)
+	}
+
+	if _, err := csv2groups(bytes.NewBufferString(csvData), []colSpec{{index: 2}}, &colSpec{index: 3}, true); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("Expected error %q, got %q instead", ErrInvalidColumn, err)
+	}
+}
@@ -5,54 +5,77 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"runtime"
+	"text/tabwriter"
 )
 
 func main() {
 	// verify and parse arguments
-	op := flag.String("op", "sum", "Operation to be executed")
-	column := flag.Int("col", 1, "CSV column on which to execute operation")
+	op := flag.String("op", "sum", "Operations to be executed, comma separated: sum, avg, min, max, count, median, pNN, stddev, variance, mode, distinct")
+	column := flag.String("col", "1", "CSV columns on which to execute operations, by number or header name, comma separated")
+	noHeader := flag.Bool("no-header", false, "CSV files have no header row")
+	groupBy := flag.String("group-by", "", "CSV column, by number or header name, whose values group the results")
 
 	flag.Parse()
 
-	if err := run(flag.Args(), *op, *column, os.Stdout); err != nil {
+	if err := run(flag.Args(), *op, *column, *groupBy, *noHeader, os.Stdout); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run(filenames []string, op string, column int, out io.Writer) error {
-	var opFunc statsFunc
-
+func run(filenames []string, op string, column string, groupBy string, noHeader bool, out io.Writer) error {
 	// filename validation
 	if len(filenames) == 0 {
 		return ErrNoFiles
 	}
 
 	// column validation
-	if column < 1 {
-		return fmt.Errorf("%w: %d", ErrInvalidColumn, column)
+	cols, err := parseCols(column)
+	if err != nil {
+		return err
+	}
+
+	var key *colSpec
+	if groupBy != "" {
+		keys, err := parseCols(groupBy)
+		if err != nil {
+			return err
+		}
+
+		if len(keys) != 1 {
+			return fmt.Errorf("%w: results can only be grouped by one column, got %q", ErrInvalidColumn, groupBy)
+		}
+
+		key = &keys[0]
+	}
+
+	if noHeader {
+		all := cols
+		if key != nil {
+			all = append(all[:len(all):len(all)], *key)
+		}
+
+		for _, c := range all {
+			if c.name != "" {
+				return fmt.Errorf("%w: %q can't be found without a header", ErrInvalidColumn, c.name)
+			}
+		}
 	}
 
-	// validate the operation and define the opFunc accordingly
-	switch op {
-	case "sum":
-		opFunc = sum
-	case "avg":
-		opFunc = avg
-	case "min":
-		opFunc = min
-	case "max":
-		opFunc = max
-	default:
-		return fmt.Errorf("%w: %s", ErrInvalidOperation, op)
+	// validate the operations
+	ops, err := parseOps(op)
+	if err != nil {
+		return err
 	}
 
-	consolidate := make([]float64, 0)
+	// values of each column per group, all in the "" group if not grouping
+	consolidate := map[string][][]float64{}
 
 	// creating channels to receive results and errors of operations
-	resCh := make(chan []float64)
+	resCh := make(chan map[string][][]float64)
 	errCh := make(chan error)
 	filesCh := make(chan string)
 	doneCh := make(chan struct{})	// doesn't need to send data, only signals
@@ -80,8 +103,8 @@ func run(filenames []string, op string, column int, out io.Writer) error {
 					errCh <- fmt.Errorf("Cannot open file: %w", err)
 				}
 
-				// parse the CSV into a slice of float64 numbers
-				data, err := csv2float(f, column)
+				// parse the CSV into slices of float64 numbers per group
+				data, err := csv2groups(f, cols, key, !noHeader)
 				if err != nil {
 					errCh <- err
 				}
@@ -105,10 +128,110 @@ func run(filenames []string, op string, column int, out io.Writer) error {
 		case err := <-errCh:
 			return err
 		case data := <-resCh:
-			consolidate = append(consolidate, data...)
+			for k, values := range data {
+				group, ok := consolidate[k]
+				if !ok {
+					group = make([][]float64, len(cols))
+					consolidate[k] = group
+				}
+
+				for i := range values {
+					group[i] = append(group[i], values[i]...)
+				}
+			}
 		case <-doneCh:
-			_, err := fmt.Fprintln(out, opFunc(consolidate))
-			return err
+			return printResults(out, ops, results(ops, cols, key, consolidate))
+		}
+	}
+}
+
+// resultTable holds the results of the operations for each column, or for
+// each group and column, with the labels identifying each row
+type resultTable struct {
+	labels []string    // names of the label cells starting each row
+	rows   []resultRow
+}
+
+type resultRow struct {
+	labels []string
+	values []float64
+}
+
+// results applies ops to the values of each group and column, sorting the
+// groups by key. Rows are only labeled by group if key is set, and by
+// column if there's more than one
+func results(ops []operation, cols []colSpec, key *colSpec, groups map[string][][]float64) resultTable {
+	var t resultTable
+
+	if key != nil {
+		t.labels = append(t.labels, key.label)
+	}
+	if len(cols) > 1 {
+		t.labels = append(t.labels, "column")
+	}
+
+	// files without rows have no groups
+	if key == nil && len(groups) == 0 {
+		groups[""] = make([][]float64, len(cols))
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for i, c := range cols {
+			var labels []string
+			if key != nil {
+				labels = append(labels, k)
+			}
+			if len(cols) > 1 {
+				labels = append(labels, c.label)
+			}
+
+			t.rows = append(t.rows, resultRow{labels, applyOps(ops, groups[k][i])})
+		}
+	}
+
+	return t
+}
+
+// printResults prints a single result as is, or a table with the row labels
+// followed by a column for each operation
+func printResults(out io.Writer, ops []operation, t resultTable) error {
+	if len(t.labels) == 0 && len(ops) == 1 {
+		_, err := fmt.Fprintln(out, t.rows[0].values[0])
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	header := append([]string{}, t.labels...)
+	for _, op := range ops {
+		header = append(header, op.name)
+	}
+	printRow(tw, header)
+
+	for _, r := range t.rows {
+		row := append([]string{}, r.labels...)
+		for _, v := range r.values {
+			row = append(row, fmt.Sprint(v))
+		}
+		printRow(tw, row)
+	}
+
+	return tw.Flush()
+}
+
+// printRow writes cells to tw as a tab separated line
+func printRow(tw io.Writer, cells []string) {
+	for i, c := range cells {
+		if i > 0 {
+			fmt.Fprint(tw, "\t")
 		}
+		fmt.Fprint(tw, c)
 	}
+	fmt.Fprintln(tw)
 }
\ No newline at end of file
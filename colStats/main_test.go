@@ -12,24 +12,40 @@ import (
 func TestRun(t *testing.T) {
 	testCases := []struct{
 		name string
-		col int
+		col string
+		groupBy string
+		noHeader bool
 		op string
 		exp string
 		files []string
 		expErr error
 	}{
-		{name: "RunAvg1File", col: 3, op: "avg", exp: "227.6\n", files: []string{"./testdata/example.csv"}, expErr: nil},
-		{name: "RunAvgMultiFiles", col: 3, op: "avg", exp: "233.84\n", files: []string{"./testdata/example.csv", "./testdata/example2.csv"}, expErr: nil},
-		{name: "RunFailRead", col: 2, op: "avg", exp: "", files: []string{"./testdata/example.csv", "./testdata/fakefile.csv"}, expErr: os.ErrNotExist},
-		{name: "RunFailColumn", col: 0, op: "avg", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidColumn},
-		{name: "RunFailNoFiles", col: 2, op: "avg", exp: "", files: []string{}, expErr: ErrNoFiles},
-		{name: "RunFailOperation", col: 2, op: "invalid", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidOperation},
+		{name: "RunAvg1File", col: "3", op: "avg", exp: "227.6\n", files: []string{"./testdata/example.csv"}, expErr: nil},
+		{name: "RunAvgMultiFiles", col: "3", op: "avg", exp: "233.84\n", files: []string{"./testdata/example.csv", "./testdata/example2.csv"}, expErr: nil},
+		{name: "RunMultiOps", col: "3", op: "sum,avg,p90", exp: "sum   avg    p90\n1138  227.6  237.2\n", files: []string{"./testdata/example.csv"}, expErr: nil},
+		{name: "RunMedianMultiFiles", col: "3", op: "median", exp: "238\n", files: []string{"./testdata/example.csv", "./testdata/example2.csv"}, expErr: nil},
+		{name: "RunByName", col: "Response Time", op: "sum", exp: "1538\n", files: []string{"./testdata/example.csv", "./testdata/reordered.csv"}, expErr: nil},
+		{name: "RunMultiColumns", col: "Response Time,Bytes", op: "sum,max", exp: "column         sum    max\nResponse Time  1138   238\nBytes          17172  3822\n", files: []string{"./testdata/example.csv"}, expErr: nil},
+		{name: "RunNoHeader", col: "3,4", noHeader: true, op: "sum", exp: "column  sum\n3       120\n4       1200\n", files: []string{"./testdata/noheader.csv"}, expErr: nil},
+		{name: "RunFailColumnName", col: "Latency", op: "sum", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunFailNoHeaderName", col: "Bytes", noHeader: true, op: "sum", exp: "", files: []string{"./testdata/noheader.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunGroupBy", col: "latency", groupBy: "region", op: "avg,count", exp: "region  avg  count\nap      15   1\neu      15   2\nus      40   2\n", files: []string{"./testdata/regions.csv", "./testdata/regions2.csv"}, expErr: nil},
+		{name: "RunGroupByMultiColumns", col: "latency,bytes", groupBy: "region", op: "sum", exp: "region  column   sum\nap      latency  15\nap      bytes    150\neu      latency  30\neu      bytes    300\nus      latency  80\nus      bytes    800\n", files: []string{"./testdata/regions.csv", "./testdata/regions2.csv"}, expErr: nil},
+		{name: "RunGroupByNumber", col: "2", groupBy: "1", op: "max", exp: "1   max\neu  20\nus  30\n", files: []string{"./testdata/regions.csv"}, expErr: nil},
+		{name: "RunFailGroupByMultiple", col: "latency", groupBy: "region,bytes", op: "sum", exp: "", files: []string{"./testdata/regions.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunFailGroupByName", col: "latency", groupBy: "zone", op: "sum", exp: "", files: []string{"./testdata/regions.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunFailGroupByNoHeader", col: "3", groupBy: "region", noHeader: true, op: "sum", exp: "", files: []string{"./testdata/noheader.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunFailRead", col: "2", op: "avg", exp: "", files: []string{"./testdata/example.csv", "./testdata/fakefile.csv"}, expErr: os.ErrNotExist},
+		{name: "RunFailColumn", col: "0", op: "avg", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidColumn},
+		{name: "RunFailNoFiles", col: "2", op: "avg", exp: "", files: []string{}, expErr: ErrNoFiles},
+		{name: "RunFailOperation", col: "2", op: "invalid", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidOperation},
+		{name: "RunFailMultiOperation", col: "2", op: "sum,invalid", exp: "", files: []string{"./testdata/example.csv"}, expErr: ErrInvalidOperation},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			var res bytes.Buffer
-			err := run(tc.files, tc.op, tc.col, &res)
+			err := run(tc.files, tc.op, tc.col, tc.groupBy, tc.noHeader, &res)
 
 			// check for erros if expErr is not nil (errors are expected)
 			// two cases: either nil or some other error
@@ -67,7 +83,7 @@ func BenchmarkRunAvg(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if err := run(filenames, "avg", 2, io.Discard); err != nil {
+		if err := run(filenames, "avg", "2", "", false, io.Discard); err != nil {
 			b.Error(err)
 		}
 	}
@@ -82,7 +98,7 @@ func BenchmarkRunMin(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if err := run(filenames, "min", 2, io.Discard); err != nil {
+		if err := run(filenames, "min", "2", "", false, io.Discard); err != nil {
 			b.Error(err)
 		}
 	}
@@ -97,7 +113,7 @@ func BenchmarkRunMax(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if err := run(filenames, "max", 2, io.Discard); err != nil {
+		if err := run(filenames, "max", "2", "", false, io.Discard); err != nil {
 			b.Error(err)
 		}
 	}
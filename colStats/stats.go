@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operation is a statistical function selected with -op. Functions with
+// sorted set expect their input in ascending order, so the data is sorted
+// only once no matter how many of them are selected
+type operation struct {
+	name   string
+	fn     statsFunc
+	sorted bool
+}
+
+// parseOps parses a comma separated list of operations, like sum,avg,p99
+func parseOps(ops string) ([]operation, error) {
+	var res []operation
+
+	for _, name := range strings.Split(ops, ",") {
+		op, err := parseOp(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, op)
+	}
+
+	return res, nil
+}
+
+func parseOp(name string) (operation, error) {
+	switch name {
+	case "sum":
+		return operation{name, sum, false}, nil
+	case "avg":
+		return operation{name, avg, false}, nil
+	case "min":
+		return operation{name, min, false}, nil
+	case "max":
+		return operation{name, max, false}, nil
+	case "count":
+		return operation{name, count, false}, nil
+	case "variance":
+		return operation{name, variance, false}, nil
+	case "stddev":
+		return operation{name, stddev, false}, nil
+	case "median":
+		return operation{name, percentile(50), true}, nil
+	case "mode":
+		return operation{name, mode, true}, nil
+	case "distinct":
+		return operation{name, distinct, true}, nil
+	}
+
+	// percentiles from p0 to p100, like p90 or p99.9
+	if strings.HasPrefix(name, "p") {
+		p, err := strconv.ParseFloat(name[1:], 64)
+		if err == nil && p >= 0 && p <= 100 {
+			return operation{name, percentile(p), true}, nil
+		}
+	}
+
+	return operation{}, fmt.Errorf("%w: %s", ErrInvalidOperation, name)
+}
+
+// applyOps executes ops on data, returning their results in the same order.
+// data is sorted in place if any operation requires it
+func applyOps(ops []operation, data []float64) []float64 {
+	for _, op := range ops {
+		if op.sorted {
+			sort.Float64s(data)
+			break
+		}
+	}
+
+	res := make([]float64, len(ops))
+	for i, op := range ops {
+		res[i] = op.fn(data)
+	}
+
+	return res
+}
+
+func count(data []float64) float64 {
+	return float64(len(data))
+}
+
+// variance returns the sample variance of data, computed in a single pass
+// with Welford's algorithm to avoid losing precision on large values. It's
+// undefined for less than two values
+func variance(data []float64) float64 {
+	if len(data) < 2 {
+		return math.NaN()
+	}
+
+	var mean, m2 float64
+
+	for i, v := range data {
+		delta := v - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (v - mean)
+	}
+
+	return m2 / float64(len(data)-1)
+}
+
+func stddev(data []float64) float64 {
+	return math.Sqrt(variance(data))
+}
+
+// percentile returns a function computing the p-th percentile of sorted
+// data, interpolating linearly between the closest values
+func percentile(p float64) statsFunc {
+	return func(data []float64) float64 {
+		if len(data) == 0 {
+			return math.NaN()
+		}
+
+		h := float64(len(data)-1) * p / 100
+		lo := math.Floor(h)
+		i := int(lo)
+
+		if i+1 >= len(data) {
+			return data[len(data)-1]
+		}
+
+		return data[i] + (h-lo)*(data[i+1]-data[i])
+	}
+}
+
+// mode returns the most frequent value in sorted data, or the smallest of
+// them if there's a tie
+func mode(data []float64) float64 {
+	if len(data) == 0 {
+		return math.NaN()
+	}
+
+	best, bestRun := data[0], 0
+
+	for i := 0; i < len(data); {
+		j := i + 1
+		for j < len(data) && data[j] == data[i] {
+			j++
+		}
+
+		if j-i > bestRun {
+			best, bestRun = data[i], j-i
+		}
+
+		i = j
+	}
+
+	return best
+}
+
+// distinct returns the number of different values in sorted data
+func distinct(data []float64) float64 {
+	n := 0
+
+	for i, v := range data {
+		if i == 0 || v != data[i-1] {
+			n++
+		}
+	}
+
+	return float64(n)
+}
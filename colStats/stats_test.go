@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestStatsOperations(t *testing.T) {
+	data := [][]float64{
+		{10, 20, 15, 30, 45, 50, 100, 30},
+		{-10, -20},
+		{102, 37, 44, 57, 67, 129},
+		{7},
+	}
+
+	testCases := []struct {
+		name string
+		op   string
+		exp  []float64
+	}{
+		{"Count", "count", []float64{8, 2, 6, 1}},
+		{"Median", "median", []float64{30, -15, 62, 7}},
+		{"P0", "p0", []float64{10, -20, 37, 7}},
+		{"P90", "p90", []float64{65, -11, 115.5, 7}},
+		{"P99", "p99", []float64{96.5, -10.1, 127.65, 7}},
+		{"P100", "p100", []float64{100, -10, 129, 7}},
+		{"Variance", "variance", []float64{828.5714285714286, 50, 1281.0666666666666, math.NaN()}},
+		{"StdDev", "stddev", []float64{28.78491668515698, 7.0710678118654755, 35.791991655490015, math.NaN()}},
+		{"Mode", "mode", []float64{30, -20, 37, 7}},
+		{"Distinct", "distinct", []float64{7, 2, 6, 1}},
+	}
+
+	for _, tc := range testCases {
+		op, err := parseOp(tc.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for k, exp := range tc.exp {
+			name := fmt.Sprintf("%sData%d", tc.name, k)
+			t.Run(name, func(t *testing.T) {
+				d := append([]float64{}, data[k]...)
+				if op.sorted {
+					sort.Float64s(d)
+				}
+
+				res := op.fn(d)
+
+				if math.IsNaN(exp) != math.IsNaN(res) || math.Abs(res-exp) > 1e-9 {
+					t.Errorf("Expected %g, got %g instead", exp, res)
+				}
+			})
+		}
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	// a header only CSV file has no values to compute
+	testCases := []struct {
+		op  string
+		exp float64
+	}{
+		{"sum", 0},
+		{"avg", math.NaN()},
+		{"min", math.NaN()},
+		{"max", math.NaN()},
+		{"count", 0},
+		{"variance", math.NaN()},
+		{"stddev", math.NaN()},
+		{"median", math.NaN()},
+		{"p90", math.NaN()},
+		{"mode", math.NaN()},
+		{"distinct", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.op, func(t *testing.T) {
+			op, err := parseOp(tc.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := op.fn([]float64{})
+
+			if math.IsNaN(tc.exp) != math.IsNaN(res) || (!math.IsNaN(res) && res != tc.exp) {
+				t.Errorf("Expected %g, got %g instead", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestParseOps(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ops    string
+		exp    []string
+		expErr error
+	}{
+		{name: "Single", ops: "sum", exp: []string{"sum"}},
+		{name: "Multiple", ops: "sum, avg,p99", exp: []string{"sum", "avg", "p99"}},
+		{name: "Fraction", ops: "p99.9", exp: []string{"p99.9"}},
+		{name: "InvalidOp", ops: "sum,total", expErr: ErrInvalidOperation},
+		{name: "InvalidPercentile", ops: "p101", expErr: ErrInvalidOperation},
+		{name: "InvalidPercentileNumber", ops: "pxx", expErr: ErrInvalidOperation},
+		{name: "Empty", ops: "", expErr: ErrInvalidOperation},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ops, err := parseOps(tc.ops)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(ops) != len(tc.exp) {
+				t.Fatalf("Expected %d operations, got %d", len(tc.exp), len(ops))
+			}
+
+			for i, op := range ops {
+				if op.name != tc.exp[i] {
+					t.Errorf("Expected operation %q, got %q", tc.exp[i], op.name)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOps(t *testing.T) {
+	ops, err := parseOps("sum,median,max,count")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []float64{3, 1, 2, 10}
+	exp := []float64{16, 2.5, 10, 4}
+
+	res := applyOps(ops, data)
+
+	for i := range exp {
+		if res[i] != exp[i] {
+			t.Errorf("Expected %v, got %v instead", exp, res)
+			break
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// relative links to Markdown files, optionally followed by a fragment
+var mdLinkRe = regexp.MustCompile(`href="([^":#?]+)\.md(#[^"]*)?"`)
+
+// buildConfig holds the options of the build subcommand
+type buildConfig struct {
+	src    string // source directory with Markdown files
+	out    string // output directory for the generated site
+	config        // options used to render every page
+}
+
+// page represents a generated HTML page listed in the index
+type page struct {
+	Title string
+	Path  string
+}
+
+// parses the build subcommand flags and runs the site build
+func buildCmd(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	src := flags.String("src", "", "Source directory with Markdown files")
+	outDir := flags.String("out", "site", "Output directory")
+	tFname := flags.String("t", "", "Alternate template name")
+	toc := flags.Bool("toc", false, "Add table of contents to the default template")
+	policy := flags.String("policy", "ugc", "Sanitization policy: strict, ugc or trusted")
+	policyFile := flags.String("policy-file", "", "YAML file with extra allowed elements and attributes")
+	mermaid := flags.Bool("mermaid", false, "Render mermaid diagrams in the browser")
+	graphviz := flags.Bool("graphviz", false, "Render dot diagrams to SVG with Graphviz")
+	ext := flags.String("ext", "gfm", "Markdown extension set: gfm or common")
+	theme := flags.String("theme", "", "Built-in theme: light or dark")
+	tdir := flags.String("tdir", "", "Template directory with a base layout and partials")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *src == "" {
+		flags.Usage()
+		return fmt.Errorf("%w: -src is required", ErrInvalidSource)
+	}
+
+	cfg := buildConfig{
+		src: *src,
+		out: *outDir,
+		config: config{
+			tFname:     *tFname,
+			toc:        *toc,
+			policy:     *policy,
+			policyFile: *policyFile,
+			mermaid:    *mermaid,
+			graphviz:   *graphviz,
+			ext:        *ext,
+			theme:      *theme,
+			tdir:       *tdir,
+		},
+	}
+
+	return build(cfg, out)
+}
+
+// build converts every Markdown file under cfg.src into HTML under cfg.out,
+// copies all other files as assets and generates an index page
+func build(cfg buildConfig, out io.Writer) error {
+	info, err := os.Stat(cfg.src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInvalidSource, cfg.src)
+	}
+
+	absOut, err := filepath.Abs(cfg.out)
+	if err != nil {
+		return err
+	}
+
+	var pages []page
+	hasIndex := false
+
+	err = filepath.WalkDir(cfg.src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// hidden files and directories, like .git, are never published
+		if path != cfg.src && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// never descend into the output directory if it lives inside src
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOut {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(cfg.src, path)
+		if err != nil {
+			return err
+		}
+
+		if filepath.Ext(path) != ".md" {
+			return copyAsset(path, filepath.Join(cfg.out, rel), out)
+		}
+
+		relHTML := strings.TrimSuffix(rel, ".md") + ".html"
+		if relHTML == "index.html" {
+			hasIndex = true
+		}
+
+		title, err := buildPage(path, rel, filepath.Join(cfg.out, relHTML), cfg.config, out)
+		if err != nil {
+			return err
+		}
+
+		pages = append(pages, page{Title: title, Path: filepath.ToSlash(relHTML)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if hasIndex {
+		return nil
+	}
+
+	return buildIndex(pages, filepath.Join(cfg.out, "index.html"), cfg.config, out)
+}
+
+// converts a single Markdown file, returning the title used for the index
+func buildPage(path, rel, dest string, cfg config, out io.Writer) (string, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	fm, _ := parseFrontMatter(input)
+
+	htmlData, err := parseContent(input, rel, cfg)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+
+	if err := saveHTML(dest, rewriteLinks(htmlData)); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintln(out, dest)
+
+	if fm.Title != "" {
+		return fm.Title, nil
+	}
+
+	return strings.TrimSuffix(filepath.Base(rel), ".md"), nil
+}
+
+// rewriteLinks points relative links to Markdown files to the generated HTML
+func rewriteLinks(data []byte) []byte {
+	return mdLinkRe.ReplaceAll(data, []byte(`href="${1}.html${2}"`))
+}
+
+// renders a page listing all generated pages using the site template
+func buildIndex(pages []page, dest string, cfg config, out io.Writer) error {
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Path < pages[j].Path
+	})
+
+	var list bytes.Buffer
+	list.WriteString("<ul>\n")
+	for _, p := range pages {
+		fmt.Fprintf(&list, "<li><a href=\"%s\">%s</a></li>\n",
+			template.HTMLEscapeString(p.Path), template.HTMLEscapeString(p.Title))
+	}
+	list.WriteString("</ul>\n")
+
+	c := content{
+		Title:    "Index",
+		Meta:     map[string]interface{}{},
+		Body:     template.HTML(list.String()),
+		Filename: "index.html",
+	}
+
+	htmlData, err := renderTemplate(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	if err := saveHTML(dest, htmlData); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, dest)
+	return err
+}
+
+// copies a non Markdown file into the site keeping its permissions
+func copyAsset(src, dest string, out io.Writer) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, in); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, dest)
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	outDir := t.TempDir()
+
+	var out bytes.Buffer
+	if err := build(buildConfig{src: "./testdata/docs", out: outDir}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		file     string
+		contains []string
+	}{
+		{name: "RewriteLinks", file: "intro.html", contains: []string{
+			"<title>Introduction</title>",
+			`href="guide/setup.html#install"`,
+			`href="https://go.dev/doc.md"`,
+		}},
+		{name: "NestedPage", file: "guide/setup.html", contains: []string{`href="../intro.html"`}},
+		{name: "CopyAsset", file: "guide/style.css", contains: []string{"color: blue"}},
+		{name: "Index", file: "index.html", contains: []string{
+			`<a href="guide/setup.html">setup</a>`,
+			`<a href="intro.html">Introduction</a>`,
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(outDir, tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, exp := range tc.contains {
+				if !strings.Contains(string(data), exp) {
+					t.Errorf("Expected %s to contain %q, got %q instead", tc.file, exp, data)
+				}
+			}
+		})
+	}
+
+	expLines := 4
+	if lines := strings.Count(out.String(), "\n"); lines != expLines {
+		t.Errorf("Expected %d generated files, got %d instead", expLines, lines)
+	}
+
+	// hidden files and directories, like .git, are never published
+	for _, hidden := range []string{".private", ".draft.html", ".draft.md"} {
+		if _, err := os.Stat(filepath.Join(outDir, hidden)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s not to be published, got %v", hidden, err)
+		}
+	}
+}
+
+func TestBuildInvalidSource(t *testing.T) {
+	err := build(buildConfig{src: "./testdata/test1.md", out: t.TempDir()}, &bytes.Buffer{})
+	if !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Expected error %q, got %q instead", ErrInvalidSource, err)
+	}
+}
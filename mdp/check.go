@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// issue represents a problem found while checking a Markdown file
+type issue struct {
+	kind   string
+	target string
+}
+
+func (i issue) String() string {
+	return fmt.Sprintf("%s: %s", i.kind, i.target)
+}
+
+// linkChecker verifies the links and images of a Markdown document.
+// External URLs are only requested when offline is false
+type linkChecker struct {
+	baseDir string
+	offline bool
+	client  *http.Client
+}
+
+func newLinkChecker(baseDir string, offline bool) *linkChecker {
+	return &linkChecker{
+		baseDir: baseDir,
+		offline: offline,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// check parses input and returns every broken relative link, missing image,
+// duplicated heading anchor and unreachable external URL, in document order
+func (c *linkChecker) check(input []byte) []issue {
+	_, input = parseFrontMatter(input)
+
+	md := blackfriday.New(blackfriday.WithExtensions(
+		blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs))
+	doc := md.Parse(input)
+
+	var (
+		issues  []issue
+		links   []*blackfriday.Node
+		anchors = map[string]int{}
+	)
+
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+
+		switch node.Type {
+		case blackfriday.Heading:
+			id := node.HeadingID
+			if id == "" {
+				id = blackfriday.SanitizedAnchorName(nodeText(node))
+			}
+
+			anchors[id]++
+			if anchors[id] == 2 {
+				issues = append(issues, issue{"duplicate anchor", "#" + id})
+			}
+		case blackfriday.Link, blackfriday.Image:
+			links = append(links, node)
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	// external URLs are requested only once per document
+	checked := map[string]bool{}
+
+	for _, node := range links {
+		dest := string(node.LinkData.Destination)
+
+		kind := "broken link"
+		if node.Type == blackfriday.Image {
+			kind = "missing image"
+		}
+
+		u, err := url.Parse(dest)
+		if err != nil {
+			issues = append(issues, issue{kind, dest})
+			continue
+		}
+
+		switch {
+		case u.Scheme == "http" || u.Scheme == "https":
+			if c.offline || checked[dest] {
+				continue
+			}
+			checked[dest] = true
+
+			if err := c.checkURL(dest); err != nil {
+				issues = append(issues, issue{"unreachable url", fmt.Sprintf("%s (%s)", dest, err)})
+			}
+		case u.Scheme != "" || u.Host != "":
+			// mailto, ftp and friends are not verified
+		case u.Path == "":
+			if u.Fragment != "" && anchors[u.Fragment] == 0 {
+				issues = append(issues, issue{"broken anchor", dest})
+			}
+		case filepath.IsAbs(u.Path) || u.Path[0] == '/':
+			// site root relative paths can't be resolved from the file
+		default:
+			if _, err := os.Stat(filepath.Join(c.baseDir, filepath.FromSlash(u.Path))); err != nil {
+				issues = append(issues, issue{kind, dest})
+			}
+		}
+	}
+
+	return issues
+}
+
+// checkURL requests rawURL, falling back to GET for servers refusing HEAD
+func (c *linkChecker) checkURL(rawURL string) error {
+	resp, err := c.client.Head(rawURL)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = c.client.Get(rawURL)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// runCheck reports the issues found in input to out, failing if there's any
+func runCheck(input []byte, filename, baseDir string, out io.Writer, offline bool) error {
+	issues := newLinkChecker(baseDir, offline).check(input)
+
+	for _, i := range issues {
+		fmt.Fprintf(out, "%s: %s\n", filename, i)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%w: %d issues found in %s", ErrCheckFailed, len(issues), filename)
+	}
+
+	return nil
+}
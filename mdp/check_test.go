@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinkChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	input := fmt.Sprintf(`# Intro
+
+## Usage
+
+## Usage
+
+[ok](other.md#other) [broken](nothere.md) [anchor](#intro) [bad anchor](#nope)
+[mail](mailto:me@example.com) [root](/docs/page.html)
+
+![logo](logo.gif) ![missing](missing.png)
+
+[up](%[1]s/up) [down](%[1]s/missing)
+`, ts.URL)
+
+	testCases := []struct {
+		name    string
+		offline bool
+		exp     []issue
+	}{
+		{name: "Online", offline: false, exp: []issue{
+			{"duplicate anchor", "#usage"},
+			{"broken link", "nothere.md"},
+			{"broken anchor", "#nope"},
+			{"missing image", "missing.png"},
+			{"unreachable url", ts.URL + "/missing (status 404)"},
+		}},
+		{name: "Offline", offline: true, exp: []issue{
+			{"duplicate anchor", "#usage"},
+			{"broken link", "nothere.md"},
+			{"broken anchor", "#nope"},
+			{"missing image", "missing.png"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues := newLinkChecker("./testdata/check", tc.offline).check([]byte(input))
+
+			if len(issues) != len(tc.exp) {
+				t.Fatalf("Expected %v, got %v instead", tc.exp, issues)
+			}
+
+			for i, exp := range tc.exp {
+				if issues[i] != exp {
+					t.Errorf("Expected %v, got %v instead", exp, issues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	var out bytes.Buffer
+
+	if err := run("./testdata/check/other.md", nil, &out, config{check: true, offline: true}); err != nil {
+		t.Errorf("Unexpected error: %q", err)
+	}
+
+	input := bytes.NewBufferString("[broken](nothere.md)\n")
+	err := run("", input, &out, config{check: true, offline: true})
+	if !errors.Is(err, ErrCheckFailed) {
+		t.Errorf("Expected error %q, got %q instead", ErrCheckFailed, err)
+	}
+
+	exp := "stdin: broken link: nothere.md\n"
+	if out.String() != exp {
+		t.Errorf("Expected %q, got %q instead", exp, out.String())
+	}
+}
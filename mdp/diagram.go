@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"os/exec"
+	"time"
+)
+
+const (
+	mermaidScript = `<script type="module">
+import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs";
+mermaid.initialize({ startOnLoad: true });
+</script>`
+
+	// dotTimeout limits how long dot may take to render a single graph
+	dotTimeout = 10 * time.Second
+)
+
+// graphvizRenderer renders dot blocks to inline SVG using the dot executable
+type graphvizRenderer struct {
+	dotPath string
+	timeout time.Duration
+}
+
+// newGraphvizRenderer locates dot in PATH. Without it, blocks show their source
+func newGraphvizRenderer() *graphvizRenderer {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		dotPath = ""
+	}
+
+	return &graphvizRenderer{dotPath: dotPath, timeout: dotTimeout}
+}
+
+func (g *graphvizRenderer) render(source []byte) ([]byte, error) {
+	if g.dotPath == "" {
+		return sourceBlock("dot", source), nil
+	}
+
+	// a graph taking too long is shown as source, so untrusted content
+	// can't hang the conversion
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, g.dotPath, "-Tsvg")
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return sourceBlock("dot", source), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dot: %w: %s", err, stderr.Bytes())
+	}
+
+	// drop the XML prolog and doctype, keeping only the svg element
+	if i := bytes.Index(out, []byte("<svg")); i > 0 {
+		out = out[i:]
+	}
+
+	return append(append([]byte("<div class=\"diagram\">\n"), out...), "</div>\n"...), nil
+}
+
+// mermaidRenderer leaves mermaid blocks for the client side script to render
+type mermaidRenderer struct{}
+
+func (mermaidRenderer) render(source []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString("<pre class=\"mermaid\">\n")
+	template.HTMLEscape(&buf, source)
+	buf.WriteString("</pre>\n")
+
+	return buf.Bytes(), nil
+}
+
+// diagramRenderers returns the block renderers enabled by cfg, by language
+func diagramRenderers(cfg config) map[string]blockRenderer {
+	renderers := map[string]blockRenderer{}
+
+	if cfg.graphviz {
+		g := newGraphvizRenderer()
+		renderers["dot"] = g
+		renderers["graphviz"] = g
+	}
+
+	if cfg.mermaid {
+		renderers["mermaid"] = mermaidRenderer{}
+	}
+
+	return renderers
+}
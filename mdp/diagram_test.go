@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRenderer returns a fixed output, or an error, for any block
+type fakeRenderer struct {
+	out string
+	err error
+}
+
+func (f fakeRenderer) render(source []byte) ([]byte, error) {
+	return []byte(f.out), f.err
+}
+
+func TestBlockHook(t *testing.T) {
+	input := []byte("```fake\nA -> B\n```\n\n```broken\n<x>\n```\n\n```go\nfmt.Println()\n```\n")
+
+	hook := newBlockHook(map[string]blockRenderer{
+		"fake":   fakeRenderer{out: "<svg><g></g></svg>\n"},
+		"broken": fakeRenderer{err: errors.New("failed")},
+	})
+
+	output, _ := renderMarkdown(input, hook, extensionSets["gfm"])
+
+	// the strict policy would remove the svg if it wasn't restored afterwards
+	p, err := newPolicy("strict", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := string(hook.restore(sanitize(p, output), diagramPolicy(p)))
+
+	exp := []string{
+		"<svg><g></g></svg>",
+		`<pre><code class="language-broken">&lt;x&gt;`,
+		"fmt.Println()",
+	}
+
+	for _, e := range exp {
+		if !strings.Contains(res, e) {
+			t.Errorf("Expected %q in %q", e, res)
+		}
+	}
+
+	if strings.Contains(res, "mdp-block-") {
+		t.Errorf("Expected placeholders to be replaced, got %q", res)
+	}
+}
+
+func TestDiagramPolicy(t *testing.T) {
+	// dot turns the URL attribute into links like these
+	svg := `<svg width="62pt" viewBox="0 0 62 116" xmlns:xlink="http://www.w3.org/1999/xlink">
+<g id="node1" class="node"><title>a</title>
+<a xlink:href="javascript:alert(1)" xlink:title="a"><ellipse fill="none" stroke="black" cx="27" cy="-90" rx="27" ry="18"/></a>
+<a xlink:href=" JaVaScRiPt:alert(2)"><text x="27">b</text></a>
+<a href="data:text/html;base64,PHNjcmlwdD4=" onclick="alert(3)"><path d="M27,-71.7"/></a>
+<a xlink:href="https://example.com/docs#a"><text x="27">c</text></a>
+<a xlink:href="#node2"><text x="27">d</text></a>
+</g>
+<script>alert(4)</script>
+</svg>`
+
+	hook := newBlockHook(map[string]blockRenderer{"dot": fakeRenderer{out: svg}})
+	output, _ := renderMarkdown([]byte("```dot\ndigraph { a [URL=\"javascript:alert(1)\"] }\n```\n"), hook, extensionSets["gfm"])
+
+	for _, name := range []string{"strict", "ugc"} {
+		t.Run(name, func(t *testing.T) {
+			p, err := newPolicy(name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := strings.ToLower(string(hook.restore(sanitize(p, output), diagramPolicy(p))))
+
+			for _, e := range []string{"<svg", "<ellipse", "<title>a</title>", `xlink:href="https://example.com/docs#a"`, `xlink:href="#node2"`} {
+				if !strings.Contains(res, e) {
+					t.Errorf("Expected %q in %q", e, res)
+				}
+			}
+
+			for _, e := range []string{"javascript", "data:", "onclick", "<script", "alert"} {
+				if strings.Contains(res, e) {
+					t.Errorf("Expected no %q in %q", e, res)
+				}
+			}
+		})
+	}
+
+	t.Run("trusted", func(t *testing.T) {
+		if diagramPolicy(nil) != nil {
+			t.Error("Expected trusted content to keep diagrams untouched")
+		}
+	})
+}
+
+func TestGraphvizRenderer(t *testing.T) {
+	source := []byte("digraph { a -> b }\n")
+
+	t.Run("Fallback", func(t *testing.T) {
+		res, err := (&graphvizRenderer{}).render(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exp := `<pre><code class="language-dot">digraph { a -&gt; b }`
+		if !strings.HasPrefix(string(res), exp) {
+			t.Errorf("Expected %q, got %q instead", exp, res)
+		}
+	})
+
+	t.Run("Dot", func(t *testing.T) {
+		if _, err := exec.LookPath("dot"); err != nil {
+			t.Skip("dot not installed")
+		}
+
+		res, err := newGraphvizRenderer().render(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(string(res), "<div class=\"diagram\">\n<svg") {
+			t.Errorf("Expected svg diagram, got %q instead", res)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("requires a shell script")
+		}
+
+		// a dot that never finishes shows the source instead
+		dotPath := filepath.Join(t.TempDir(), "dot")
+		if err := os.WriteFile(dotPath, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		res, err := (&graphvizRenderer{dotPath: dotPath, timeout: 100 * time.Millisecond}).render(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Expected render to stop after the timeout, took %s", elapsed)
+		}
+
+		exp := `<pre><code class="language-dot">`
+		if !strings.HasPrefix(string(res), exp) {
+			t.Errorf("Expected %q, got %q instead", exp, res)
+		}
+	})
+}
+
+func TestDiagramRenderers(t *testing.T) {
+	if r := diagramRenderers(config{}); len(r) != 0 {
+		t.Errorf("Expected no renderers by default, got %v", r)
+	}
+
+	r := diagramRenderers(config{graphviz: true, mermaid: true})
+	if r["dot"] == nil || r["dot"] != r["graphviz"] {
+		t.Errorf("Expected a single graphviz renderer for dot and graphviz, got %v", r)
+	}
+	if _, ok := r["mermaid"]; !ok {
+		t.Errorf("Expected mermaid renderer, got %v", r)
+	}
+}
+
+func TestParseContentMermaid(t *testing.T) {
+	input := []byte("```mermaid\ngraph TD; A-->B\n```\n")
+
+	res, err := parseContent(input, "mermaid.md", config{mermaid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"<pre class=\"mermaid\">\ngraph TD; A--&gt;B\n</pre>", "mermaid.initialize"}
+	for _, e := range exp {
+		if !strings.Contains(string(res), e) {
+			t.Errorf("Expected %q in %q", e, res)
+		}
+	}
+
+	res, err = parseContent(input, "mermaid.md", config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(res), "mermaid.initialize") {
+		t.Errorf("Expected no mermaid script, got %q", res)
+	}
+}
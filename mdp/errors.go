@@ -0,0 +1,12 @@
+package main
+
+import "errors"
+
+var (
+	ErrInvalidSource = errors.New("Invalid source directory")
+	ErrInvalidPolicy = errors.New("Invalid sanitization policy")
+	ErrInvalidExport = errors.New("Invalid export mode")
+	ErrCheckFailed = errors.New("Check failed")
+	ErrInvalidExtension = errors.New("Invalid extension set")
+	ErrInvalidTheme = errors.New("Invalid theme")
+)
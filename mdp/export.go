@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	printCSS = `<style>
+@page { size: A4; margin: 2cm; }
+@media print {
+	body { font-family: serif; font-size: 11pt; }
+	h1 { page-break-before: always; }
+	h1:first-of-type { page-break-before: avoid; }
+	h2, h3, h4 { page-break-after: avoid; }
+	pre, table, img, figure { page-break-inside: avoid; }
+	nav.toc { page-break-after: always; }
+	a[href^="http"]::after { content: " (" attr(href) ")"; }
+}
+</style>
+`
+)
+
+var (
+	imgSrcRe     = regexp.MustCompile(`(<img\b[^>]*?\bsrc=")([^"]+)(")`)
+	stylesheetRe = regexp.MustCompile(`<link\b[^>]*\brel="stylesheet"[^>]*>`)
+	hrefRe       = regexp.MustCompile(`\bhref="([^"]+)"`)
+)
+
+// exportHTML converts the rendered page according to mode. Assets referenced
+// with relative paths are resolved from baseDir
+func exportHTML(data []byte, mode, baseDir string) ([]byte, error) {
+	switch mode {
+	case "":
+		return data, nil
+	case "standalone":
+		return inlineAssets(data, baseDir)
+	case "print":
+		data, err := inlineAssets(data, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		return injectHead(data, printCSS), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExport, mode)
+	}
+}
+
+// inlineAssets embeds local images as base64 data URIs and replaces links
+// to local stylesheets with their content
+func inlineAssets(data []byte, baseDir string) ([]byte, error) {
+	var err error
+
+	data = imgSrcRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		parts := imgSrcRe.FindSubmatch(m)
+		src := html.UnescapeString(string(parts[2]))
+
+		if err != nil || !isLocalAsset(src) {
+			return m
+		}
+
+		var asset []byte
+		asset, err = os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(src)))
+		if err != nil {
+			return m
+		}
+
+		uri := fmt.Sprintf("data:%s;base64,%s", mimeType(src, asset),
+			base64.StdEncoding.EncodeToString(asset))
+
+		return bytes.Join([][]byte{parts[1], []byte(uri), parts[3]}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data = stylesheetRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		href := hrefRe.FindSubmatch(m)
+		if err != nil || href == nil {
+			return m
+		}
+
+		path := html.UnescapeString(string(href[1]))
+		if !isLocalAsset(path) {
+			return m
+		}
+
+		var css []byte
+		css, err = os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(path)))
+		if err != nil {
+			return m
+		}
+
+		return []byte(fmt.Sprintf("<style>\n%s</style>", css))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// isLocalAsset reports whether ref points to a file relative to the document
+func isLocalAsset(ref string) bool {
+	if ref == "" || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "#") {
+		return false
+	}
+
+	return !strings.Contains(ref, ":")
+}
+
+// mimeType guesses the media type from the extension, then from the content
+func mimeType(name string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+
+	return http.DetectContentType(data)
+}
+
+// injectHead adds snippet right before the closing head tag, or at the
+// beginning of the document if there isn't one
+func injectHead(data []byte, snippet string) []byte {
+	i := bytes.Index(data, []byte("</head>"))
+	if i < 0 {
+		return append([]byte(snippet), data...)
+	}
+
+	out := make([]byte, 0, len(data)+len(snippet))
+	out = append(out, data[:i]...)
+	out = append(out, snippet...)
+	out = append(out, data[i:]...)
+
+	return out
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportHTML(t *testing.T) {
+	page := `<html>
+<head>
+<link rel="stylesheet" href="style.css">
+<link rel="stylesheet" href="https://example.com/remote.css">
+</head>
+<body>
+<img src="pixel.gif" alt="local">
+<img src="https://example.com/remote.png" alt="remote">
+</body>
+</html>
+`
+
+	testCases := []struct {
+		name     string
+		mode     string
+		contains []string
+		missing  []string
+		expErr   error
+	}{
+		{name: "NoExport", mode: "", contains: []string{`src="pixel.gif"`, `href="style.css"`}},
+		{name: "Standalone", mode: "standalone",
+			contains: []string{
+				`src="data:image/gif;base64,R0lGODlh"`,
+				"<style>\nh1 { color: red; }\n</style>",
+				`src="https://example.com/remote.png"`,
+				`href="https://example.com/remote.css"`,
+			},
+			missing: []string{`href="style.css"`, "@page"}},
+		{name: "Print", mode: "print", contains: []string{"@page", "data:image/gif;base64"}},
+		{name: "FailInvalidMode", mode: "pdf", expErr: ErrInvalidExport},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := exportHTML([]byte(page), tc.mode, "./testdata/export")
+
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			for _, exp := range tc.contains {
+				if !strings.Contains(string(res), exp) {
+					t.Errorf("Expected %q in %q", exp, res)
+				}
+			}
+
+			for _, exp := range tc.missing {
+				if strings.Contains(string(res), exp) {
+					t.Errorf("Expected %q to be replaced in %q", exp, res)
+				}
+			}
+		})
+	}
+}
+
+func TestExportHTMLMissingAsset(t *testing.T) {
+	page := `<img src="missing.png">`
+
+	if _, err := exportHTML([]byte(page), "standalone", "./testdata/export"); err == nil {
+		t.Error("Expected error, got nil instead")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter represents the metadata block found at the top of a Markdown file
+type frontMatter struct {
+	Title  string
+	Author string
+	Date   string
+	Params map[string]interface{}
+}
+
+// parseFrontMatter detects a YAML (---) or TOML (+++) front matter block at
+// the beginning of input, decodes it and returns the remaining Markdown.
+// A block that isn't closed or doesn't decode to a mapping is most likely
+// a Markdown thematic break, so input is returned unchanged
+func parseFrontMatter(input []byte) (frontMatter, []byte) {
+	fm := frontMatter{Params: map[string]interface{}{}}
+
+	var delim string
+	switch {
+	case bytes.HasPrefix(input, []byte("---")):
+		delim = "---"
+	case bytes.HasPrefix(input, []byte("+++")):
+		delim = "+++"
+	default:
+		return fm, input
+	}
+
+	// the opening delimiter must be alone on the first line
+	first, rest, found := bytes.Cut(input, []byte("\n"))
+	if !found || string(bytes.TrimSpace(first)) != delim {
+		return fm, input
+	}
+
+	// look for the closing delimiter on a line of its own
+	var block []byte
+	closed := false
+	for len(rest) > 0 {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if string(bytes.TrimSpace(line)) == delim {
+			closed = true
+			break
+		}
+		block = append(block, line...)
+		block = append(block, '\n')
+	}
+
+	if !closed {
+		return fm, input
+	}
+
+	var (
+		params map[string]interface{}
+		err    error
+	)
+	if delim == "---" {
+		err = yaml.Unmarshal(block, &params)
+	} else {
+		err = toml.Unmarshal(block, &params)
+	}
+	if err != nil || params == nil {
+		return fm, input
+	}
+
+	fm.Params = params
+
+	fm.Title = metaString(fm.Params["title"])
+	fm.Author = metaString(fm.Params["author"])
+	fm.Date = metaString(fm.Params["date"])
+
+	return fm, rest
+}
+
+// metaString converts a decoded front matter value into its text form
+func metaString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02")
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expTitle string
+		expDate  string
+		expBody  string
+	}{
+		{name: "NoFrontMatter", input: "# Title\n", expBody: "# Title\n"},
+		{name: "YAML", input: "---\ntitle: Notes\ndate: 2023-10-01\n---\n# Title\n", expTitle: "Notes", expDate: "2023-10-01", expBody: "# Title\n"},
+		{name: "TOML", input: "+++\ntitle = \"Notes\"\ndate = 2023-10-01\n+++\n# Title\n", expTitle: "Notes", expDate: "2023-10-01", expBody: "# Title\n"},
+		{name: "ThematicBreak", input: "---\n# Title\n", expBody: "---\n# Title\n"},
+		{name: "ThematicBreaks", input: "---\nSome text\n---\n# Title\n", expBody: "---\nSome text\n---\n# Title\n"},
+		{name: "InvalidYAML", input: "---\ntitle: [\n---\n", expBody: "---\ntitle: [\n---\n"},
+		{name: "Empty", input: "---\n---\n", expBody: "---\n---\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm, body := parseFrontMatter([]byte(tc.input))
+
+			if fm.Title != tc.expTitle {
+				t.Errorf("Expected title %q, got %q instead", tc.expTitle, fm.Title)
+			}
+
+			if fm.Date != tc.expDate {
+				t.Errorf("Expected date %q, got %q instead", tc.expDate, fm.Date)
+			}
+
+			if string(body) != tc.expBody {
+				t.Errorf("Expected body %q, got %q instead", tc.expBody, body)
+			}
+		})
+	}
+}
+
+func TestParseContentFrontMatter(t *testing.T) {
+	input, err := os.ReadFile("./testdata/frontmatter.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := parseContent(input, "frontmatter.md", config{tFname: "./testdata/meta.html.tmpl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "<h1>Design Notes</h1>\n<p>Jane Doe 2023-10-01 platform</p>\n"
+	if !strings.HasPrefix(string(result), exp) {
+		t.Errorf("Expected result to start with %q, got %q instead", exp, result)
+	}
+
+	if strings.Contains(string(result), "author:") {
+		t.Errorf("Expected front matter to be stripped, got %q", result)
+	}
+}
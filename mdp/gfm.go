@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+// extensionSet represents the Markdown features enabled by the -ext flag.
+// Task lists and emoji aren't supported by blackfriday, so they're applied
+// to the document tree before rendering
+type extensionSet struct {
+	flags     blackfriday.Extensions
+	taskLists bool
+	emoji     bool
+}
+
+var (
+	extensionSets = map[string]extensionSet{
+		"common": {
+			flags: blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs,
+		},
+		"gfm": {
+			flags:     blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs | blackfriday.Footnotes,
+			taskLists: true,
+			emoji:     true,
+		},
+	}
+
+	taskRe  = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	emojiRe = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+	// emojis holds the shortcodes most used in READMEs and changelogs
+	emojis = map[string]string{
+		"+1":                 "👍",
+		"-1":                 "👎",
+		"arrow_right":        "➡️",
+		"beetle":             "🐞",
+		"bomb":               "💣",
+		"books":              "📚",
+		"bug":                "🐛",
+		"bulb":               "💡",
+		"construction":       "🚧",
+		"heart":              "❤️",
+		"heavy_check_mark":   "✔️",
+		"information_source": "ℹ️",
+		"lock":               "🔒",
+		"memo":               "📝",
+		"no_entry":           "⛔",
+		"package":            "📦",
+		"rocket":             "🚀",
+		"smile":              "😄",
+		"sparkles":           "✨",
+		"star":               "⭐",
+		"tada":               "🎉",
+		"thumbsdown":         "👎",
+		"thumbsup":           "👍",
+		"warning":            "⚠️",
+		"white_check_mark":   "✅",
+		"wrench":             "🔧",
+		"x":                  "❌",
+		"zap":                "⚡",
+	}
+)
+
+// extensions returns the extension set registered as name
+func extensions(name string) (extensionSet, error) {
+	if name == "" {
+		name = "gfm"
+	}
+
+	ext, ok := extensionSets[name]
+	if !ok {
+		return extensionSet{}, fmt.Errorf("%w: %s", ErrInvalidExtension, name)
+	}
+
+	return ext, nil
+}
+
+// applyGFM rewrites doc adding task list checkboxes and replacing emoji
+// shortcodes, according to ext
+func applyGFM(doc *blackfriday.Node, ext extensionSet) {
+	var tasks []*blackfriday.Node
+
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Text {
+			return blackfriday.GoToNext
+		}
+
+		if ext.emoji {
+			node.Literal = emojiRe.ReplaceAllFunc(node.Literal, func(m []byte) []byte {
+				if e, ok := emojis[string(m[1:len(m)-1])]; ok {
+					return []byte(e)
+				}
+				return m
+			})
+		}
+
+		if ext.taskLists && isTaskText(node) {
+			tasks = append(tasks, node)
+		}
+
+		return blackfriday.GoToNext
+	})
+
+	// the tree can't be modified while walking it
+	for _, node := range tasks {
+		m := taskRe.FindSubmatch(node.Literal)
+
+		box := blackfriday.NewNode(blackfriday.HTMLSpan)
+		box.Literal = []byte(`<input type="checkbox" disabled="">`)
+		if !bytes.Equal(m[1], []byte(" ")) {
+			box.Literal = []byte(`<input type="checkbox" checked="" disabled="">`)
+		}
+
+		node.Literal = append(node.Literal[:0:0], node.Literal[len(m[0]):]...)
+		node.InsertBefore(box)
+	}
+}
+
+// isTaskText reports whether node starts the first paragraph of a list item
+// with a task marker such as "[ ] " or "[x] "
+func isTaskText(node *blackfriday.Node) bool {
+	p := node.Parent
+	if node.Prev != nil || p == nil || p.Type != blackfriday.Paragraph || p.Prev != nil {
+		return false
+	}
+
+	if p.Parent == nil || p.Parent.Type != blackfriday.Item {
+		return false
+	}
+
+	return taskRe.Match(node.Literal)
+}
+
+// allowTaskLists lets the checkboxes generated for task lists through p
+func allowTaskLists(p *bluemonday.Policy) {
+	if p == nil {
+		return
+	}
+
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+}
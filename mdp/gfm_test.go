@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseContentGFM(t *testing.T) {
+	input, err := os.ReadFile("./testdata/gfm.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		ext      string
+		policy   string
+		contains []string
+		missing  []string
+		expErr   error
+	}{
+		{name: "GFM", ext: "gfm",
+			contains: []string{
+				"Release 🚀</h1>",
+				`<li><input type="checkbox" disabled="">write docs</li>`,
+				`<li><input type="checkbox" checked="" disabled="">ship it 🎉</li>`,
+				"<table>",
+				"<del>todo</del>",
+				`<a href="https://example.com" rel="nofollow">https://example.com</a>`,
+				"<code>:tada:</code>",
+				`<a href="#fn:1" rel="nofollow">1</a>`,
+				"The footnote.",
+			}},
+		{name: "Default", ext: "", contains: []string{`<input type="checkbox"`, "🚀"}},
+		{name: "Common", ext: "common",
+			contains: []string{"<table>", "<del>todo</del>", "[ ] write docs", ":rocket:"},
+			missing:  []string{"<input", `href="#fn:1"`}},
+		{name: "Strict", ext: "gfm", policy: "strict",
+			contains: []string{"write docs"},
+			missing:  []string{"<input", "checkbox"}},
+		{name: "FailInvalid", ext: "markdown", expErr: ErrInvalidExtension},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseContent(input, "gfm.md", config{ext: tc.ext, policy: tc.policy})
+
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			for _, exp := range tc.contains {
+				if !strings.Contains(string(res), exp) {
+					t.Errorf("Expected %q in %q", exp, res)
+				}
+			}
+
+			for _, exp := range tc.missing {
+				if strings.Contains(string(res), exp) {
+					t.Errorf("Expected no %q in %q", exp, res)
+				}
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ go 1.20
 
 require (
 	github.com/microcosm-cc/bluemonday v1.0.25
+	github.com/pelletier/go-toml/v2 v2.1.0
 	github.com/russross/blackfriday/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -7,23 +7,33 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"html/template"
 	"runtime"
 	"time"
-
-	"github.com/microcosm-cc/bluemonday"
-	"github.com/russross/blackfriday/v2"
 )
 
 const (
 	defaultTemplate = `<!DOCTYPE html>
 <html>
 	<head>
-		<meta http=equiv="content-type" content="text/html"; charset="utf-8">
+		<meta http-equiv="content-type" content="text/html; charset=utf-8">
 		<title>{{.Title}}</title>
+		{{- with .Author}}
+		<meta name="author" content="{{.}}">
+		{{- end}}
+		{{- with .Date}}
+		<meta name="date" content="{{.}}">
+		{{- end}}
 	</head>
 	<body>
+	{{- if .ShowTOC}}
+	{{.TOC}}
+	{{- end}}
 	{{.Body}}
+	{{- with .Scripts}}
+	{{.}}
+	{{- end}}
 	</body>
 	<footer>FILENAME: {{.Filename}}</footer>
 </html>
@@ -31,43 +41,144 @@ const (
 )
 
 // content type represents the HTML content to add into the template
+// Meta exposes every front matter key, including the ones mapped to fields
 type content struct {
 	Title string
+	Author string
+	Date string
+	Meta map[string]interface{}
+	TOC template.HTML
+	ShowTOC bool
 	Body template.HTML
+	Scripts template.HTML
 	Filename string
 }
 
+// config holds the options used to render the markdown
+type config struct {
+	tFname string // alternate template name
+	toc bool // inject table of contents into the default template
+	policy string // sanitization policy: strict, ugc or trusted
+	policyFile string // extra elements and attributes to allow
+	outFname string // output file name, "-" for stdout
+	export string // export mode: standalone or print
+	mermaid bool // render mermaid blocks on the client side
+	graphviz bool // render dot blocks to SVG with the dot executable
+	check bool // report broken links instead of rendering
+	offline bool // don't request external URLs when checking
+	ext string // markdown extension set: gfm or common
+	theme string // built-in theme name
+	tdir string // template directory with a base layout and partials
+	skipPreview bool // skip auto-preview
+}
+
 func main() {
+	// the build subcommand converts a whole directory tree
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		if err := buildCmd(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// parsing flags
-	filename := flag.String("file", "", "Markdown file to preview")
+	filename := flag.String("file", "", "Markdown file to preview, reads stdin if empty")
+	outFname := flag.String("o", "", "Output HTML file, - for stdout")
+	export := flag.String("export", "", "Export mode: standalone or print")
+	mermaid := flag.Bool("mermaid", false, "Render mermaid diagrams in the browser")
+	graphviz := flag.Bool("graphviz", false, "Render dot diagrams to SVG with Graphviz")
+	check := flag.Bool("check", false, "Check links, images and anchors instead of rendering")
+	offline := flag.Bool("offline", false, "Skip external URLs when checking")
+	ext := flag.String("ext", "gfm", "Markdown extension set: gfm or common")
+	theme := flag.String("theme", "", "Built-in theme: light or dark")
+	tdir := flag.String("tdir", "", "Template directory with a base layout and partials")
 	skipPreview := flag.Bool("s", false, "Skip auto-preview")
 	tFname := flag.String("t", "", "Alternate template name")
+	toc := flag.Bool("toc", false, "Add table of contents to the default template")
+	policy := flag.String("policy", "ugc", "Sanitization policy: strict, ugc or trusted")
+	policyFile := flag.String("policy-file", "", "YAML file with extra allowed elements and attributes")
 	flag.Parse()
 
-	// if no input, show usage info
-	if *filename == "" {
+	// if no input file and nothing piped to stdin, show usage info
+	if *filename == "" && isTerminal(os.Stdin) {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if err := run(*filename, *tFname, os.Stdout, *skipPreview); err != nil {
+	c := config {
+		tFname: *tFname,
+		toc: *toc,
+		policy: *policy,
+		policyFile: *policyFile,
+		outFname: *outFname,
+		export: *export,
+		mermaid: *mermaid,
+		graphviz: *graphviz,
+		check: *check,
+		offline: *offline,
+		ext: *ext,
+		theme: *theme,
+		tdir: *tdir,
+		skipPreview: *skipPreview,
+	}
+
+	if err := run(*filename, os.Stdin, os.Stdout, c); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run (filename, tFname string, out io.Writer, skipPreview bool) error {
-	// read all the data from the input file and check for errors
-	input, err := os.ReadFile(filename)
+func run (filename string, in io.Reader, out io.Writer, cfg config) error {
+	// read all the data from the input file, or from in if there is no
+	// file, and check for errors
+	var (
+		input []byte
+		err error
+	)
+
+	// relative assets are resolved from the markdown file directory
+	baseDir := filepath.Dir(filename)
+
+	if filename == "" {
+		input, err = io.ReadAll(in)
+		filename = "stdin"
+
+		// pipeline mode writes to stdout unless told otherwise
+		if cfg.outFname == "" {
+			cfg.outFname = "-"
+		}
+	} else {
+		input, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.check {
+		return runCheck(input, filename, baseDir, out, cfg.offline)
+	}
+
+	htmlData, err := parseContent(input, filename, cfg)
 	if err != nil {
 		return err
 	}
 
-	htmlData, err := parseContent(input, filename, tFname)
+	htmlData, err = exportHTML(htmlData, cfg.export, baseDir)
 	if err != nil {
 		return err
 	}
 
+	// explicit outputs are meant for scripts, never preview them
+	switch cfg.outFname {
+	case "":
+	case "-":
+		_, err := out.Write(htmlData)
+		return err
+	default:
+		return saveHTML(cfg.outFname, htmlData)
+	}
+
 	temp, err := os.CreateTemp("", "mdp*.html")
 	if err != nil {
 		return err
@@ -83,7 +194,7 @@ func run (filename, tFname string, out io.Writer, skipPreview bool) error {
 		return err
 	}
 
-	if skipPreview {
+	if cfg.skipPreview {
 		return nil
 	}
 
@@ -94,31 +205,74 @@ func run (filename, tFname string, out io.Writer, skipPreview bool) error {
 
 // parses the markdown file through blackfriday and bluemonday
 // for generating a valid and safe html
-func parseContent(input []byte, filename, tFname string) ([]byte, error) {
+func parseContent(input []byte, filename string, cfg config) ([]byte, error) {
+	// strip the front matter, if any, before rendering the markdown
+	fm, input := parseFrontMatter(input)
+
+	ext, err := extensions(cfg.ext)
+	if err != nil {
+		return nil, err
+	}
+
 	// parse markdown to generate valid & safe html
-	output := blackfriday.Run(input)
-	body := bluemonday.UGCPolicy().SanitizeBytes(output)
+	hook := newBlockHook(diagramRenderers(cfg))
+	output, headings := renderMarkdown(input, hook, ext)
 
-	// parse the contents of defaultTemplate into new template
-	t, err := template.New("mdp").Parse(defaultTemplate)
+	policy, err := newPolicy(cfg.policy, cfg.policyFile)
 	if err != nil {
 		return nil, err
 	}
+	// strict output stays plain text, without task list checkboxes
+	if ext.taskLists && cfg.policy != "strict" {
+		allowTaskLists(policy)
+	}
+	body := hook.restore(sanitize(policy, output), diagramPolicy(policy))
 
-	if tFname != "" {
-		t, err = template.ParseFiles(tFname)
-		if err != nil {
-			return nil, err
-		}
+	var scripts template.HTML
+	if cfg.mermaid {
+		scripts = mermaidScript
 	}
 
-	// instantiate the content type, adding the title and body
+	title := fm.Title
+	if title == "" {
+		title = "Markdown Preview Tool"
+	}
+
+	// instantiate the content type, adding the metadata and body
 	c := content {
-		Title: "Markdown Preview Tool",
+		Title: title,
+		Author: fm.Author,
+		Date: fm.Date,
+		Meta: fm.Params,
+		TOC: renderTOC(headings),
+		ShowTOC: cfg.toc,
 		Body: template.HTML(body),
+		Scripts: scripts,
 		Filename: filename,
 	}
 
+	return renderTemplate(c, cfg)
+}
+
+// executes the default template with c, or the alternate template file,
+// theme or template directory selected in cfg
+func renderTemplate(c content, cfg config) ([]byte, error) {
+	// parse the contents of defaultTemplate into new template
+	t, err := template.New("mdp").Parse(defaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cfg.tFname != "":
+		t, err = template.ParseFiles(cfg.tFname)
+	case cfg.theme != "" || cfg.tdir != "":
+		t, err = loadTheme(cfg.theme, cfg.tdir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	// create a buffer of bytes to write to file
 	var buffer bytes.Buffer
 
@@ -130,6 +284,16 @@ func parseContent(input []byte, filename, tFname string) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// reports whether f is attached to a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // 0644 permisson allows write by owner and read by anyone
 func saveHTML(outFname string, data []byte) error {
 	return os.WriteFile(outFname, data, 0644)
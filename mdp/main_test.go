@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -18,7 +19,7 @@ func TestParseContent(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	result, err := parseContent(input, inputFile, "")
+	result, err := parseContent(input, inputFile, config{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,7 +40,7 @@ func TestRun(t *testing.T) {
 	var mockStdOut bytes.Buffer
 	
 	// passing true skips the auto-preview
-	if err := run(inputFile, "", &mockStdOut, true); err != nil {
+	if err := run(inputFile, nil, &mockStdOut, config{skipPreview: true}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -64,4 +65,61 @@ func TestRun(t *testing.T) {
 	os.Remove(resultFile)
 }
 
-// add test for alternate template file
\ No newline at end of file
+// add test for alternate template file
+
+func TestRunStdin(t *testing.T) {
+	input, err := os.ReadFile(inputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = bytes.Replace(expected, []byte(inputFile), []byte("stdin"), 1)
+
+	var mockStdOut bytes.Buffer
+
+	// no file and no output name writes the html to out
+	if err := run("", bytes.NewReader(input), &mockStdOut, config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, mockStdOut.Bytes()) {
+		t.Logf("golden:\n%s\n", expected)
+		t.Logf("result:\n%s\n", mockStdOut.Bytes())
+		t.Error("Result content does not match golden file")
+	}
+}
+
+func TestRunOutputFile(t *testing.T) {
+	var mockStdOut bytes.Buffer
+
+	outFname := filepath.Join(t.TempDir(), "out.html")
+
+	// an explicit output file never triggers the preview
+	if err := run(inputFile, nil, &mockStdOut, config{outFname: outFname}); err != nil {
+		t.Fatal(err)
+	}
+
+	if mockStdOut.Len() != 0 {
+		t.Errorf("Expected no output, got %q instead", mockStdOut.String())
+	}
+
+	result, err := os.ReadFile(outFname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, result) {
+		t.Logf("golden:\n%s\n", expected)
+		t.Logf("result:\n%s\n", result)
+		t.Error("Result content does not match golden file")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile represents the extra elements and attributes allowed on top of
+// a base policy. An attribute without elements is allowed globally
+type policyFile struct {
+	Elements   []string            `yaml:"elements"`
+	Attributes map[string][]string `yaml:"attributes"`
+}
+
+// newPolicy returns the sanitization policy selected by name, extended with
+// the rules from fname if given. A nil policy means content is trusted
+func newPolicy(name, fname string) (*bluemonday.Policy, error) {
+	var p *bluemonday.Policy
+
+	switch name {
+	case "", "ugc":
+		p = bluemonday.UGCPolicy()
+	case "strict":
+		p = bluemonday.StrictPolicy()
+	case "trusted", "none":
+		if fname != "" {
+			return nil, fmt.Errorf("%w: policy file cannot extend %q", ErrInvalidPolicy, name)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, name)
+	}
+
+	if fname == "" {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var pf policyFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidPolicy, fname, err)
+	}
+
+	if len(pf.Elements) > 0 {
+		p.AllowElements(pf.Elements...)
+	}
+
+	for attr, elements := range pf.Attributes {
+		if len(elements) == 0 {
+			p.AllowAttrs(attr).Globally()
+			continue
+		}
+		p.AllowAttrs(attr).OnElements(elements...)
+	}
+
+	return p, nil
+}
+
+// safeLink matches links to http, https and mailto URLs, or relative ones,
+// so diagram links can't run scripts with javascript: or data: URLs
+var safeLink = regexp.MustCompile(`^(?:(?i:https?|mailto):|[^:]*(?:[/?#]|$))`)
+
+// diagramPolicy returns the policy applied to rendered diagrams, which the
+// page policy would strip. It allows the SVG elements and attributes
+// Graphviz generates and safe links only. Diagrams are trusted along with
+// the page if page is nil
+func diagramPolicy(page *bluemonday.Policy) *bluemonday.Policy {
+	if page == nil {
+		return nil
+	}
+
+	elements := []string{"div", "pre", "code", "svg", "g", "a", "title", "path", "polygon",
+		"polyline", "ellipse", "circle", "rect", "line", "text", "tspan", "defs",
+		"lineargradient", "radialgradient", "stop"}
+
+	p := bluemonday.NewPolicy()
+	p.AllowElements(elements...)
+	p.AllowNoAttrs().OnElements(elements...)
+	p.AllowElementsContent("title")
+
+	p.AllowAttrs("id", "class", "transform", "fill", "fill-opacity", "stroke", "stroke-width",
+		"stroke-dasharray", "stroke-opacity", "opacity", "points", "d", "cx", "cy", "r", "rx", "ry",
+		"x", "y", "x1", "y1", "x2", "y2", "width", "height", "viewbox", "text-anchor",
+		"font-family", "font-size", "font-weight", "font-style", "offset", "stop-color",
+		"stop-opacity", "gradientunits", "gradienttransform").Globally()
+	p.AllowAttrs("xlink:title", "target").OnElements("a")
+
+	p.AllowAttrs("href", "xlink:href").Matching(safeLink).OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+
+	return p
+}
+
+// sanitize applies the policy p to data, returning it untouched if p is nil
+func sanitize(p *bluemonday.Policy, data []byte) []byte {
+	if p == nil {
+		return data
+	}
+
+	return p.SanitizeBytes(data)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewPolicy(t *testing.T) {
+	input := `<details open class="note"><summary>More</summary><p>Hidden <b>text</b></p></details><script>alert(1)</script>`
+
+	testCases := []struct {
+		name     string
+		policy   string
+		file     string
+		contains []string
+		missing  []string
+		expErr   error
+	}{
+		{name: "Default", policy: "", missing: []string{`class="note"`, "<script>"}},
+		{name: "UGC", policy: "ugc", contains: []string{"<b>text</b>"}, missing: []string{`class="note"`, "<script>"}},
+		{name: "Strict", policy: "strict", contains: []string{"Hidden text"}, missing: []string{"<b>", "<p>"}},
+		{name: "Trusted", policy: "trusted", contains: []string{"<script>", "<details"}},
+		{name: "PolicyFile", policy: "ugc", file: "./testdata/policy.yaml",
+			contains: []string{`<details open="" class="note">`, "<summary>More</summary>"},
+			missing:  []string{"<script>"}},
+		{name: "FailInvalidPolicy", policy: "invalid", expErr: ErrInvalidPolicy},
+		{name: "FailTrustedPolicyFile", policy: "trusted", file: "./testdata/policy.yaml", expErr: ErrInvalidPolicy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newPolicy(tc.policy, tc.file)
+
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			res := string(sanitize(p, []byte(input)))
+
+			for _, exp := range tc.contains {
+				if !strings.Contains(res, exp) {
+					t.Errorf("Expected %q in %q", exp, res)
+				}
+			}
+
+			for _, exp := range tc.missing {
+				if strings.Contains(res, exp) {
+					t.Errorf("Expected %q to be removed from %q", exp, res)
+				}
+			}
+		})
+	}
+}
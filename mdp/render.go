@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+// blockRenderer converts the source of a fenced code block into HTML
+type blockRenderer interface {
+	render(source []byte) ([]byte, error)
+}
+
+// blockHook wraps the HTML renderer replacing fenced code blocks whose
+// language has a blockRenderer by placeholders. The rendered blocks are
+// put back by restore once the page has been sanitized, since diagrams
+// use markup the page policies don't allow, and are sanitized on their own
+type blockHook struct {
+	*blackfriday.HTMLRenderer
+	renderers map[string]blockRenderer
+	nonce     string
+	blocks    [][]byte
+}
+
+func newBlockHook(renderers map[string]blockRenderer) *blockHook {
+	// the nonce prevents markdown authors from forging placeholders
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return &blockHook{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags: blackfriday.CommonHTMLFlags,
+		}),
+		renderers: renderers,
+		nonce:     hex.EncodeToString(b),
+	}
+}
+
+// RenderNode implements blackfriday.Renderer
+func (h *blockHook) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if node.Type != blackfriday.CodeBlock || !node.IsFenced {
+		return h.HTMLRenderer.RenderNode(w, node, entering)
+	}
+
+	lang := ""
+	if fields := strings.Fields(string(node.Info)); len(fields) > 0 {
+		lang = fields[0]
+	}
+
+	r, ok := h.renderers[lang]
+	if !ok {
+		return h.HTMLRenderer.RenderNode(w, node, entering)
+	}
+
+	out, err := r.render(node.Literal)
+	if err != nil {
+		out = sourceBlock(lang, node.Literal)
+	}
+
+	fmt.Fprintf(w, "<div>%s</div>\n", h.placeholder(len(h.blocks)))
+	h.blocks = append(h.blocks, out)
+
+	return blackfriday.GoToNext
+}
+
+func (h *blockHook) placeholder(i int) string {
+	return fmt.Sprintf("mdp-block-%s-%d", h.nonce, i)
+}
+
+// restore replaces the placeholders in data with the rendered blocks,
+// sanitized with the policy p. Strict policies strip the wrapping div,
+// leaving only the placeholder text
+func (h *blockHook) restore(data []byte, p *bluemonday.Policy) []byte {
+	for i, block := range h.blocks {
+		placeholder := []byte(h.placeholder(i))
+
+		wrapped := []byte(fmt.Sprintf("<div>%s</div>", placeholder))
+		if bytes.Contains(data, wrapped) {
+			placeholder = wrapped
+		}
+
+		data = bytes.Replace(data, placeholder, sanitize(p, block), 1)
+	}
+
+	return data
+}
+
+// renders the markdown to HTML through hook with the extensions in ext,
+// giving every heading a unique anchor, and returns the headings found in
+// document order
+func renderMarkdown(input []byte, hook *blockHook, ext extensionSet) ([]byte, []heading) {
+	md := blackfriday.New(blackfriday.WithExtensions(ext.flags))
+	doc := md.Parse(input)
+
+	applyGFM(doc, ext)
+	headings := collectHeadings(doc)
+
+	var buf bytes.Buffer
+	hook.RenderHeader(&buf, doc)
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		return hook.RenderNode(&buf, node, entering)
+	})
+	hook.RenderFooter(&buf, doc)
+
+	return buf.Bytes(), headings
+}
+
+// sourceBlock shows the block source as a regular code block
+func sourceBlock(lang string, source []byte) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<pre><code class=\"language-%s\">", lang)
+	template.HTMLEscape(&buf, source)
+	buf.WriteString("</code></pre>\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// themesFS holds the built-in themes. Every theme extends the templates
+// in themes/layout, usually by defining the "style" block
+//
+//go:embed themes
+var themesFS embed.FS
+
+// loadTheme parses the built-in theme name, if any, followed by the
+// templates in tdir, which may override its layout, blocks and partials.
+// The returned template executes the "base" layout
+func loadTheme(name, tdir string) (*template.Template, error) {
+	t := template.New("mdp")
+
+	if name != "" {
+		if name == "layout" || !isDir(themesFS, path.Join("themes", name)) {
+			return nil, fmt.Errorf("%w: %s, available: %s", ErrInvalidTheme, name,
+				strings.Join(themes(), ", "))
+		}
+
+		for _, dir := range []string{"themes/layout", path.Join("themes", name)} {
+			var err error
+			if t, err = t.ParseFS(themesFS, path.Join(dir, "*.tmpl")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if tdir != "" {
+		files, err := filepath.Glob(filepath.Join(tdir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%w: no templates found in %s", ErrInvalidTheme, tdir)
+		}
+
+		if t, err = t.ParseFiles(files...); err != nil {
+			return nil, err
+		}
+	}
+
+	base := t.Lookup("base")
+	if base == nil {
+		return nil, fmt.Errorf("%w: missing \"base\" template", ErrInvalidTheme)
+	}
+
+	return base, nil
+}
+
+// themes lists the names of the built-in themes
+func themes() []string {
+	entries, err := fs.ReadDir(themesFS, "themes")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "layout" {
+			names = append(names, e.Name())
+		}
+	}
+
+	return names
+}
+
+func isDir(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
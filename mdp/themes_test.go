@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateTheme(t *testing.T) {
+	c := content{Title: "Notes", Author: "Jane", Body: "<p>body</p>", Filename: "notes.md"}
+
+	testCases := []struct {
+		name     string
+		theme    string
+		tdir     string
+		contains []string
+		expErr   error
+	}{
+		{name: "Light", theme: "light", contains: []string{
+			"<title>Notes</title>", "background: #ffffff", `<span class="title">Notes</span>`,
+			"<main>\n\t\t<p>body</p>", "<footer>FILENAME: notes.md</footer>",
+		}},
+		{name: "Dark", theme: "dark", contains: []string{"background: #0d1117", "<p>body</p>"}},
+		{name: "OverridePartial", theme: "dark", tdir: "./testdata/theme", contains: []string{
+			"background: #0d1117", "<footer>Custom footer for Notes</footer>",
+		}},
+		{name: "FailInvalidTheme", theme: "blue", expErr: ErrInvalidTheme},
+		{name: "FailNoBase", tdir: "./testdata/theme", expErr: ErrInvalidTheme},
+		{name: "FailEmptyDir", tdir: "./testdata/export", expErr: ErrInvalidTheme},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := renderTemplate(c, config{theme: tc.theme, tdir: tc.tdir})
+
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			for _, exp := range tc.contains {
+				if !strings.Contains(string(res), exp) {
+					t.Errorf("Expected %q in %q", exp, res)
+				}
+			}
+		})
+	}
+}
+
+func TestThemes(t *testing.T) {
+	exp := "dark,light"
+
+	if res := strings.Join(themes(), ","); res != exp {
+		t.Errorf("Expected %q, got %q instead", exp, res)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// heading represents an entry of the table of contents
+type heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// walks the document assigning stable, unique IDs to the headings
+func collectHeadings(doc *blackfriday.Node) []heading {
+	var headings []heading
+	used := map[string]bool{}
+
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Heading || node.IsTitleblock {
+			return blackfriday.GoToNext
+		}
+
+		text := nodeText(node)
+
+		id := node.HeadingID
+		if id == "" {
+			id = blackfriday.SanitizedAnchorName(text)
+		}
+		if id == "" {
+			id = "section"
+		}
+
+		// duplicated titles get a numeric suffix, as GitHub does
+		unique := id
+		for i := 1; used[unique]; i++ {
+			unique = fmt.Sprintf("%s-%d", id, i)
+		}
+		used[unique] = true
+		node.HeadingID = unique
+
+		headings = append(headings, heading{Level: node.Level, ID: unique, Text: text})
+		return blackfriday.SkipChildren
+	})
+
+	return headings
+}
+
+// concatenates the literal text of all children of node
+func nodeText(node *blackfriday.Node) string {
+	var sb strings.Builder
+
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+			sb.Write(n.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+
+	return strings.TrimSpace(sb.String())
+}
+
+// builds a nested list of links to the headings
+func renderTOC(headings []heading) template.HTML {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<nav class=\"toc\">\n")
+
+	// levels holds the heading level of every open list
+	var levels []int
+	for i, h := range headings {
+		switch {
+		case len(levels) == 0 || h.Level > levels[len(levels)-1]:
+			sb.WriteString("<ul>\n")
+			levels = append(levels, h.Level)
+		default:
+			for len(levels) > 1 && h.Level < levels[len(levels)-1] && h.Level <= levels[len(levels)-2] {
+				sb.WriteString("</li>\n</ul>\n")
+				levels = levels[:len(levels)-1]
+			}
+			if i > 0 {
+				sb.WriteString("</li>\n")
+			}
+		}
+
+		fmt.Fprintf(&sb, "<li><a href=\"#%s\">%s</a>",
+			template.HTMLEscapeString(h.ID), template.HTMLEscapeString(h.Text))
+	}
+
+	for range levels {
+		sb.WriteString("</li>\n</ul>\n")
+	}
+	sb.WriteString("</nav>\n")
+
+	return template.HTML(sb.String())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownHeadings(t *testing.T) {
+	input := "# Intro\n\n## Setup\n\n## Setup\n\n### Install `mdp`\n\n## Custom {#my-id}\n"
+
+	output, headings := renderMarkdown([]byte(input), newBlockHook(nil), extensionSets["gfm"])
+
+	exp := []heading{
+		{Level: 1, ID: "intro", Text: "Intro"},
+		{Level: 2, ID: "setup", Text: "Setup"},
+		{Level: 2, ID: "setup-1", Text: "Setup"},
+		{Level: 3, ID: "install-mdp", Text: "Install mdp"},
+		{Level: 2, ID: "my-id", Text: "Custom"},
+	}
+
+	if len(headings) != len(exp) {
+		t.Fatalf("Expected %d headings, got %d instead", len(exp), len(headings))
+	}
+
+	for i, h := range exp {
+		if headings[i] != h {
+			t.Errorf("Expected heading %v, got %v instead", h, headings[i])
+		}
+
+		anchor := `id="` + h.ID + `"`
+		if !strings.Contains(string(output), anchor) {
+			t.Errorf("Expected output to contain %q, got %q instead", anchor, output)
+		}
+	}
+}
+
+func TestRenderTOC(t *testing.T) {
+	headings := []heading{
+		{Level: 1, ID: "a", Text: "A"},
+		{Level: 2, ID: "b", Text: "B"},
+		{Level: 3, ID: "c", Text: "C"},
+		{Level: 2, ID: "d", Text: "D & E"},
+		{Level: 1, ID: "f", Text: "F"},
+	}
+
+	exp := `<nav class="toc">
+<ul>
+<li><a href="#a">A</a><ul>
+<li><a href="#b">B</a><ul>
+<li><a href="#c">C</a></li>
+</ul>
+</li>
+<li><a href="#d">D &amp; E</a></li>
+</ul>
+</li>
+<li><a href="#f">F</a></li>
+</ul>
+</nav>
+`
+
+	if res := string(renderTOC(headings)); res != exp {
+		t.Errorf("Expected %q, got %q instead", exp, res)
+	}
+
+	if res := renderTOC(nil); res != "" {
+		t.Errorf("Expected empty TOC, got %q instead", res)
+	}
+}
+
+func TestParseContentTOC(t *testing.T) {
+	input := []byte("# Intro\n\n## Setup\n")
+
+	withTOC, err := parseContent(input, "toc.md", config{toc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(withTOC), `<a href="#setup">Setup</a>`) {
+		t.Errorf("Expected table of contents, got %q instead", withTOC)
+	}
+
+	noTOC, err := parseContent(input, "toc.md", config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(noTOC), `class="toc"`) {
+		t.Errorf("Expected no table of contents, got %q instead", noTOC)
+	}
+}
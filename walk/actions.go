@@ -2,23 +2,28 @@ package main
 
 import (
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-func filterOut(path, ext string, minSize int64, info os.FileInfo) bool {
-	if info.IsDir() || info.Size() < minSize {
+// specialModes are the file types skipped unless cfg.specials is set
+const specialModes = fs.ModeSocket | fs.ModeDevice | fs.ModeCharDevice | fs.ModeNamedPipe | fs.ModeIrregular
+
+func filterOut(path string, info os.FileInfo, cfg config) bool {
+	if info.IsDir() {
 		return true
 	}
 
-	if ext != "" && filepath.Ext(path) != ext {
+	if !cfg.specials && info.Mode()&specialModes != 0 {
 		return true
 	}
 
-	return false
+	return !matchCriteria(path, info, cfg)
 }
 
 
@@ -27,38 +32,130 @@ func listFile(path string, out io.Writer) error {
 	return err
 }
 
-func delFile(path string, delLogger *log.Logger) error {
-	if err := os.Remove(path); err != nil {
-		return err
+// delFile removes path, or moves it to trash if it isn't nil, returning
+// where it was moved to
+func delFile(path string, trash *trashCan) (string, error) {
+	if trash != nil {
+		return trash.put(path)
+	}
+
+	return "", os.Remove(path)
+}
+
+// delBundled deletes the files written to a complete archive file. With
+// cfg.keepGoing, failures are added to the errors recorded by the walk in
+// walkErr instead of stopping at the first one
+func delBundled(paths []string, cfg config, audit *auditLog, walkErr error) error {
+	var errs walkErrors
+	if we, ok := walkErr.(*walkErrors); ok {
+		errs = *we
+	}
+
+	for _, path := range paths {
+		err := audit.track("delete", path, func() (string, error) {
+			return delFile(path, cfg.trash)
+		})
+		if err == nil {
+			continue
+		}
+
+		if !cfg.keepGoing {
+			return err
+		}
+		errs.record(path, err)
+	}
+
+	return errs.err()
+}
+
+// dryRunSummary accumulates what a dry run would have done
+type dryRunSummary struct {
+	files int // files matched
+	bytes int64 // size of the matched files
+	freed int64 // size of the files that would be deleted
+}
+
+// dryRunFile reports the actions that would be executed on path, without
+// touching the filesystem
+func dryRunFile(root, path string, info os.FileInfo, cfg config, sum *dryRunSummary, out io.Writer) error {
+	if cfg.archive != "" {
+		targetPath, err := archivePath(cfg.archive, root, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "ARCHIVE %s -> %s\n", path, targetPath)
+	}
+
+	if cfg.archiveFile != "" {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "ARCHIVE %s -> %s:%s\n", path, cfg.archiveFile, filepath.ToSlash(rel))
+	}
+
+	if cfg.del && cfg.trash != nil {
+		fmt.Fprintf(out, "TRASH %s\n", path)
+	} else if cfg.del {
+		fmt.Fprintf(out, "DELETE %s\n", path)
+		sum.freed += info.Size()
 	}
 
-	delLogger.Println(path)
+	sum.files++
+	sum.bytes += info.Size()
+
 	return nil
 }
 
-func archiveFile(destDir, root, path string) error {
-	info, err := os.Stat(destDir)
+func (s dryRunSummary) print(out io.Writer) error {
+	_, err := fmt.Fprintf(out, "DRY RUN: %d files, %d bytes matched, %d bytes would be freed\n",
+		s.files, s.bytes, s.freed)
+	return err
+}
+
+// checkDir returns an error if dir doesn't exist or isn't a directory
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
 	if err != nil {
 		return err
 	}
 
 	if !info.IsDir() {
-		return fmt.Errorf("%s is not a directory", destDir)
+		return fmt.Errorf("%s is not a directory", dir)
 	}
 
+	return nil
+}
+
+// archivePath returns where archiveFile stores path, mirroring its
+// location relative to root inside destDir
+func archivePath(destDir, root, path string) (string, error) {
 	relDir, err := filepath.Rel(root, filepath.Dir(path))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	dest := fmt.Sprintf("%s.gz", filepath.Base(path))
-	targetPath := filepath.Join(destDir, relDir, dest)
+	return filepath.Join(destDir, relDir, dest), nil
+}
+
+func archiveFile(destDir, root, path string) error {
+	if err := checkDir(destDir); err != nil {
+		return err
+	}
+
+	targetPath, err := archivePath(destDir, root, path)
+	if err != nil {
+		return err
+	}
 
 	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 		return err
 	}
 
-	out, err := os.OpenFile(targetPath, os.O_RDWR | os.O_CREATE, 0644)
+	out, err := os.OpenFile(targetPath, os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
@@ -70,8 +167,15 @@ func archiveFile(destDir, root, path string) error {
 	}
 	defer in.Close()
 
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	// name and modification time allow restoreFile to recreate the file
 	zw := gzip.NewWriter(out)
 	zw.Name = filepath.Base(path)
+	zw.ModTime = info.ModTime()
 
 	if _, err := io.Copy(zw, in); err != nil {
 		return err
@@ -82,4 +186,80 @@ func archiveFile(destDir, root, path string) error {
 	}
 
 	return out.Close()
-}
\ No newline at end of file
+}
+
+// restoreFile decompresses path, a file created by archiveFile inside
+// archiveDir, back to its original location under root, returning it.
+// Existing files are only overwritten if force is set
+func restoreFile(archiveDir, root, path string, force bool) (string, error) {
+	relDir, err := filepath.Rel(archiveDir, filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), ".gz")
+	if zr.Name != "" {
+		name = filepath.Base(zr.Name)
+	}
+
+	targetPath := filepath.Join(root, relDir, name)
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Lstat(targetPath); err == nil && !force {
+		return "", fmt.Errorf("%w: %s", ErrFileExists, targetPath)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	// the file is decompressed next to the target and moved into place once
+	// complete, so a corrupt archive doesn't leave a truncated file behind
+	out, err := os.CreateTemp(filepath.Dir(targetPath), "."+name+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	if _, err := io.Copy(out, zr); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return "", err
+	}
+
+	// archives created before modification times were stored have none
+	mtime := zr.ModTime
+	if mtime.IsZero() {
+		info, err := in.Stat()
+		if err != nil {
+			return "", err
+		}
+		mtime = info.ModTime()
+	}
+
+	if err := os.Chtimes(out.Name(), mtime, mtime); err != nil {
+		return "", err
+	}
+
+	return targetPath, os.Rename(out.Name(), targetPath)
+}
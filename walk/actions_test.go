@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFilterOut(t *testing.T) {
@@ -27,11 +32,125 @@ func TestFilterOut(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			f := filterOut(tc.file, tc.ext, tc.minSize, info)
+			f := filterOut(tc.file, info, config{ext: tc.ext, size: tc.minSize})
 
 			if f != tc.expected {
 				t.Errorf("Expected '%t', got '%t' instead\n", tc.expected, f)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// fakeInfo describes a file of any type without creating it
+type fakeInfo struct {
+	name string
+	mode fs.FileMode
+}
+
+func (f fakeInfo) Name() string       { return f.name }
+func (f fakeInfo) Size() int64        { return 0 }
+func (f fakeInfo) Mode() fs.FileMode  { return f.mode }
+func (f fakeInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f fakeInfo) Sys() any           { return nil }
+
+func TestFilterOutSpecial(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     fs.FileMode
+		specials bool
+		expected bool
+	}{
+		{"Regular", 0644, false, false},
+		{"Symlink", fs.ModeSymlink, false, false},
+		{"Dir", fs.ModeDir, true, true},
+		{"Socket", fs.ModeSocket, false, true},
+		{"SocketIncluded", fs.ModeSocket, true, false},
+		{"Device", fs.ModeDevice, false, true},
+		{"CharDevice", fs.ModeDevice | fs.ModeCharDevice, false, true},
+		{"NamedPipe", fs.ModeNamedPipe, false, true},
+		{"NamedPipeIncluded", fs.ModeNamedPipe, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := fakeInfo{name: "file", mode: tc.mode}
+
+			if f := filterOut("file", info, config{specials: tc.specials}); f != tc.expected {
+				t.Errorf("Expected '%t', got '%t' instead\n", tc.expected, f)
+			}
+		})
+	}
+}
+
+func TestRestoreFileCorrupt(t *testing.T) {
+	root := t.TempDir()
+	archiveDir := t.TempDir()
+
+	path := filepath.Join(root, "file.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("log line\n", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := archiveFile(archiveDir, root, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// truncate the gzip stream, keeping its header
+	gzPath := filepath.Join(archiveDir, "file.log.gz")
+	if err := os.Truncate(gzPath, 30); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := restoreFile(archiveDir, root, gzPath, false)
+	if err == nil || !strings.Contains(err.Error(), gzPath) {
+		t.Errorf("Expected error mentioning %s, got %v", gzPath, err)
+	}
+
+	// nothing is left behind to block the next restore
+	left, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("Expected no files left in %s, got %d", root, len(left))
+	}
+
+	if _, err := restoreFile(archiveDir, root, gzPath, false); errors.Is(err, ErrFileExists) {
+		t.Errorf("Expected the original error again, got %q", err)
+	}
+}
+
+func TestArchiveFileShrunk(t *testing.T) {
+	root := t.TempDir()
+	archiveDir := t.TempDir()
+
+	// archiving a file again after it shrinks must not keep stale bytes
+	path := filepath.Join(root, "file.log")
+	for _, data := range []string{strings.Repeat("long log line\n", 1000), "short\n"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := archiveFile(archiveDir, root, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := restoreFile(archiveDir, root, filepath.Join(archiveDir, "file.log.gz"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "short\n" {
+		t.Errorf("Expected %q, got %q instead", "short\n", data)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// auditRecord describes an action executed on a file
+type auditRecord struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Checksum string    `json:"checksum,omitempty"`
+	Target   string    `json:"target,omitempty"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// auditLog writes a JSON object per line for every action executed. A nil
+// auditLog executes actions without recording them
+type auditLog struct {
+	enc *json.Encoder
+}
+
+// newAuditLog returns an auditLog writing to w, or nil if w is nil
+func newAuditLog(w io.Writer) *auditLog {
+	if w == nil {
+		return nil
+	}
+
+	return &auditLog{enc: json.NewEncoder(w)}
+}
+
+// track executes fn, the named action on path, recording its result along
+// with the size and SHA-256 checksum path had before. fn returns where
+// the file was moved or copied to, if anywhere
+func (a *auditLog) track(action, path string, fn func() (string, error)) error {
+	if a == nil {
+		_, err := fn()
+		return err
+	}
+
+	rec := auditRecord{Action: action, Path: path}
+
+	// the file may be gone once the action is executed
+	if info, err := os.Stat(path); err == nil {
+		rec.Size = info.Size()
+		if info.Mode().IsRegular() {
+			rec.Checksum, _ = hashFile(path, -1)
+		}
+	}
+
+	target, err := fn()
+
+	rec.Time = time.Now()
+	rec.Target = target
+	rec.Result = "ok"
+	if err != nil {
+		rec.Result = "error"
+		rec.Error = err.Error()
+	}
+
+	if werr := a.enc.Encode(rec); err == nil {
+		err = werr
+	}
+
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAudit(t *testing.T, r io.Reader) []auditRecord {
+	t.Helper()
+
+	var recs []auditRecord
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			t.Fatalf("Invalid audit line %q: %s", s.Text(), err)
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func TestAuditTrack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.log")
+	if err := os.WriteFile(path, []byte("audit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("audit"))
+	errTest := errors.New("test error")
+
+	testCases := []struct {
+		name      string
+		target    string
+		err       error
+		expResult string
+	}{
+		{name: "Success", target: "dest.gz", expResult: "ok"},
+		{name: "Failure", err: errTest, expResult: "error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := newAuditLog(&buf).track("archive", path, func() (string, error) {
+				return tc.target, tc.err
+			})
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+
+			recs := readAudit(t, &buf)
+			if len(recs) != 1 {
+				t.Fatalf("Expected 1 audit record, got %d", len(recs))
+			}
+
+			rec := recs[0]
+			if rec.Action != "archive" || rec.Path != path || rec.Target != tc.target {
+				t.Errorf("Unexpected record %+v", rec)
+			}
+
+			if rec.Size != 5 || rec.Checksum != hex.EncodeToString(sum[:]) {
+				t.Errorf("Expected size 5 and checksum %x, got %d and %s", sum, rec.Size, rec.Checksum)
+			}
+
+			if rec.Result != tc.expResult {
+				t.Errorf("Expected result %q, got %q", tc.expResult, rec.Result)
+			}
+
+			if tc.err != nil && rec.Error != tc.err.Error() {
+				t.Errorf("Expected error %q logged, got %q", tc.err, rec.Error)
+			}
+
+			if rec.Time.IsZero() {
+				t.Error("Expected timestamp to be set")
+			}
+		})
+	}
+}
+
+func TestAuditNil(t *testing.T) {
+	called := false
+
+	err := newAuditLog(nil).track("list", "missing", func() (string, error) {
+		called = true
+		return "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !called {
+		t.Error("Expected action to be executed without audit log")
+	}
+}
+
+func TestRunAudit(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 2, ".gz": 1})
+	defer cleanup()
+
+	archiveDir := t.TempDir()
+
+	var logBuffer bytes.Buffer
+	cfg := config{ext: ".log", archive: archiveDir, del: true, wLog: &logBuffer}
+
+	if err := run(tempDir, io.Discard, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]int{}
+	for _, rec := range readAudit(t, &logBuffer) {
+		actions[rec.Action]++
+
+		if rec.Result != "ok" || rec.Checksum == "" {
+			t.Errorf("Unexpected record %+v", rec)
+		}
+
+		if rec.Action == "archive" && filepath.Dir(rec.Target) != archiveDir {
+			t.Errorf("Expected archive target in %s, got %s", archiveDir, rec.Target)
+		}
+	}
+
+	if actions["archive"] != 2 || actions["delete"] != 2 || len(actions) != 2 {
+		t.Errorf("Expected 2 archive and 2 delete records, got %v", actions)
+	}
+}
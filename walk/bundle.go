@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archiver writes files into a single archive format. Symbolic links are
+// stored as links to target instead of the content they point to
+type archiver interface {
+	addFile(name string, info fs.FileInfo, r io.Reader) error
+	addLink(name string, info fs.FileInfo, target string) error
+	Close() error
+}
+
+// tarArchiver writes a tar stream through a compressor
+type tarArchiver struct {
+	tw   *tar.Writer
+	comp io.WriteCloser
+}
+
+func (t *tarArchiver) addFile(name string, info fs.FileInfo, r io.Reader) error {
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(t.tw, r)
+	return err
+}
+
+func (t *tarArchiver) addLink(name string, info fs.FileInfo, target string) error {
+	hdr, err := tar.FileInfoHeader(info, target)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+
+	return t.tw.WriteHeader(hdr)
+}
+
+func (t *tarArchiver) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+
+	return t.comp.Close()
+}
+
+// zipArchiver writes deflated zip entries
+type zipArchiver struct {
+	zw *zip.Writer
+}
+
+func (z *zipArchiver) addFile(name string, info fs.FileInfo, r io.Reader) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// addLink stores the link target as the entry content, as zip and unzip do
+func (z *zipArchiver) addLink(name string, info fs.FileInfo, target string) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (z *zipArchiver) Close() error {
+	return z.zw.Close()
+}
+
+// bundle streams files found under root into a single archive, keeping
+// their relative paths, permissions and modification times, and writes a
+// manifest next to it with their SHA-256 checksums, in sha256sum format
+type bundle struct {
+	archiver
+	root     string
+	fname    string
+	f        *os.File
+	manifest *os.File
+}
+
+// newBundle creates the archive fname, choosing the format by its extension
+func newBundle(fname, root string) (*bundle, error) {
+	var format string
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar.zst", ".zip"} {
+		if strings.HasSuffix(fname, ext) {
+			format = ext
+		}
+	}
+
+	if format == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, fname)
+	}
+
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var a archiver
+
+	switch format {
+	case ".tar.gz", ".tgz":
+		zw := gzip.NewWriter(f)
+		a = &tarArchiver{tw: tar.NewWriter(zw), comp: zw}
+	case ".tar.zst":
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		a = &tarArchiver{tw: tar.NewWriter(zw), comp: zw}
+	case ".zip":
+		a = &zipArchiver{zw: zip.NewWriter(f)}
+	}
+
+	manifest, err := os.Create(fname + ".sha256")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &bundle{archiver: a, root: root, fname: fname, f: f, manifest: manifest}, nil
+}
+
+// add appends path to the archive and its checksum to the manifest.
+// Symbolic links are archived as links and left out of the manifest,
+// since they have no content of their own to check
+func (b *bundle) add(path string, info fs.FileInfo) error {
+	name, err := b.name(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		return b.addLink(name, info, target)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	h := sha256.New()
+	if err := b.addFile(name, info, io.TeeReader(in, h)); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(b.manifest, "%s  %s\n", hex.EncodeToString(h.Sum(nil)), name)
+	return err
+}
+
+// name returns the slash separated path stored in the archive for path
+func (b *bundle) name(path string) (string, error) {
+	rel, err := filepath.Rel(b.root, path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// owns reports whether path is the archive or its manifest, which must not
+// be added to themselves when created inside root
+func (b *bundle) owns(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	for _, own := range []*os.File{b.f, b.manifest} {
+		if ownInfo, err := own.Stat(); err == nil && os.SameFile(info, ownInfo) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *bundle) Close() error {
+	if err := b.archiver.Close(); err != nil {
+		b.f.Close()
+		b.manifest.Close()
+		return err
+	}
+
+	if err := b.f.Close(); err != nil {
+		b.manifest.Close()
+		return err
+	}
+
+	return b.manifest.Close()
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archived represents a file read back from an archive
+type archived struct {
+	mode    os.FileMode
+	modTime time.Time
+	data    string
+	link    string // target of symbolic links
+}
+
+func readArchive(t *testing.T, fname string) map[string]archived {
+	t.Helper()
+
+	files := map[string]archived{}
+
+	if strings.HasSuffix(fname, ".zip") {
+		zr, err := zip.OpenReader(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Close()
+
+			a := archived{f.Mode(), f.Modified, string(data), ""}
+			if f.Mode()&os.ModeSymlink != 0 {
+				a.data, a.link = "", string(data)
+			}
+			files[f.Name] = a
+		}
+
+		return files
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader
+	if strings.HasSuffix(fname, ".tar.zst") {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[hdr.Name] = archived{hdr.FileInfo().Mode(), hdr.ModTime, string(data), hdr.Linkname}
+	}
+
+	return files
+}
+
+func TestRunArchiveFile(t *testing.T) {
+	for _, ext := range []string{".tar.gz", ".tar.zst", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			tempDir, cleanup := createTempDir(t, map[string]int{".log": 2, ".txt": 1})
+			defer cleanup()
+
+			subDir := filepath.Join(tempDir, "sub")
+			if err := os.Mkdir(subDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			script := filepath.Join(subDir, "run.log")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := os.Chtimes(script, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			// the archive lives inside root and must not archive itself
+			archiveFile := filepath.Join(tempDir, "backup"+ext)
+
+			var buffer bytes.Buffer
+			cfg := config{ext: ".log", archiveFile: archiveFile, ordered: true}
+
+			if err := run(tempDir, &buffer, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			files := readArchive(t, archiveFile)
+
+			if len(files) != 3 {
+				t.Fatalf("Expected 3 files archived, got %d instead: %v", len(files), files)
+			}
+
+			f, ok := files["sub/run.log"]
+			if !ok {
+				t.Fatalf("Expected sub/run.log in archive, got %v", files)
+			}
+
+			if f.mode.Perm() != 0755 {
+				t.Errorf("Expected mode %v, got %v instead", os.FileMode(0755), f.mode.Perm())
+			}
+
+			if !f.modTime.Equal(mtime) {
+				t.Errorf("Expected mtime %v, got %v instead", mtime, f.modTime)
+			}
+
+			if f.data != "#!/bin/sh\n" {
+				t.Errorf("Expected content %q, got %q instead", "#!/bin/sh\n", f.data)
+			}
+
+			manifest, err := os.ReadFile(archiveFile + ".sha256")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sum := sha256.Sum256([]byte("#!/bin/sh\n"))
+			expLine := fmt.Sprintf("%s  sub/run.log\n", hex.EncodeToString(sum[:]))
+
+			if !strings.HasSuffix(string(manifest), expLine) {
+				t.Errorf("Expected manifest to end with %q, got %q instead", expLine, manifest)
+			}
+
+			if lines := strings.Count(string(manifest), "\n"); lines != 3 {
+				t.Errorf("Expected 3 manifest lines, got %d instead", lines)
+			}
+		})
+	}
+}
+
+func TestRunArchiveFileInvalid(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 1})
+	defer cleanup()
+
+	testCases := []struct {
+		name string
+		cfg  config
+	}{
+		{name: "UnknownFormat", cfg: config{archiveFile: filepath.Join(tempDir, "backup.rar")}},
+		{name: "ArchiveDirAndFile", cfg: config{archive: tempDir, archiveFile: filepath.Join(tempDir, "backup.zip")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(tempDir, io.Discard, tc.cfg)
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Errorf("Expected error %q, got %q instead", ErrInvalidArchive, err)
+			}
+		})
+	}
+}
+
+func TestRunArchiveFileDelete(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3})
+	defer cleanup()
+
+	archiveFile := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	var auditBuf bytes.Buffer
+	cfg := config{ext: ".log", archiveFile: archiveFile, del: true, wLog: &auditBuf, ordered: true}
+
+	if err := run(tempDir, io.Discard, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := readArchive(t, archiveFile); len(files) != 3 {
+		t.Errorf("Expected 3 files archived, got %d instead: %v", len(files), files)
+	}
+
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("file%d.log", i))
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s deleted, got %v", path, err)
+		}
+	}
+
+	// files are only deleted once the archive is complete
+	var actions []string
+	for _, rec := range readAudit(t, &auditBuf) {
+		actions = append(actions, rec.Action)
+	}
+
+	expActions := "archive archive archive delete delete delete"
+	if strings.Join(actions, " ") != expActions {
+		t.Errorf("Expected actions %q, got %q instead", expActions, actions)
+	}
+}
+
+func TestRunArchiveFileSymlink(t *testing.T) {
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			tempDir, cleanup := createTempDir(t, map[string]int{".log": 1})
+			defer cleanup()
+
+			links := map[string]string{"link.log": "file1.log", "broken.log": "missing.log"}
+			for name, target := range links {
+				if err := os.Symlink(target, filepath.Join(tempDir, name)); err != nil {
+					t.Skipf("Cannot create symlinks: %s", err)
+				}
+			}
+
+			archiveFile := filepath.Join(t.TempDir(), "backup"+ext)
+			cfg := config{ext: ".log", archiveFile: archiveFile, ordered: true}
+
+			if err := run(tempDir, io.Discard, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			files := readArchive(t, archiveFile)
+
+			if len(files) != 3 {
+				t.Fatalf("Expected 3 entries archived, got %d instead: %v", len(files), files)
+			}
+
+			for name, target := range links {
+				f := files[name]
+				if f.mode&os.ModeSymlink == 0 || f.link != target || f.data != "" {
+					t.Errorf("Expected %s archived as a link to %s, got %+v", name, target, f)
+				}
+			}
+
+			manifest, err := os.ReadFile(archiveFile + ".sha256")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if lines := strings.Count(string(manifest), "\n"); lines != 1 {
+				t.Errorf("Expected 1 manifest line, got %d instead: %q", lines, manifest)
+			}
+		})
+	}
+}
+
+func TestRunArchiveFileDeleteTwice(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 1})
+	defer cleanup()
+
+	archiveFile := filepath.Join(t.TempDir(), "backup.tar.gz")
+	cfg := config{ext: ".log", archiveFile: archiveFile, del: true}
+
+	if err := run(tempDir, io.Discard, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second run must not overwrite the only copy of file1.log
+	if err := os.WriteFile(filepath.Join(tempDir, "other.log"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(tempDir, io.Discard, cfg); !errors.Is(err, ErrFileExists) {
+		t.Errorf("Expected error %q, got %q instead", ErrFileExists, err)
+	}
+
+	if _, ok := readArchive(t, archiveFile)["file1.log"]; !ok {
+		t.Error("Expected file1.log to remain archived")
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "other.log")); err != nil {
+		t.Errorf("Expected other.log to exist: %s", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileDevice isn't supported on this platform, so -xdev doesn't skip anything
+func fileDevice(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// duEntry holds the size of the matched files under a directory
+type duEntry struct {
+	dir   string
+	size  int64
+	files int
+}
+
+// duSummary aggregates the size of matched files per directory, like du.
+// Every file counts towards all of its parent directories up to depth
+// levels below root, so deeper directories are folded into their ancestors.
+// A negative depth doesn't limit how deep directories are reported
+type duSummary struct {
+	root  string
+	depth int
+	dirs  map[string]*duEntry
+}
+
+func newDUSummary(root string, depth int) *duSummary {
+	return &duSummary{root: root, depth: depth, dirs: map[string]*duEntry{}}
+}
+
+// add counts the file path towards its parent directories
+func (d *duSummary) add(path string, info fs.FileInfo) error {
+	rel, err := filepath.Rel(d.root, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	parts := []string{}
+	if rel != "." {
+		parts = strings.Split(rel, string(filepath.Separator))
+	}
+
+	if d.depth >= 0 && len(parts) > d.depth {
+		parts = parts[:d.depth]
+	}
+
+	for i := 0; i <= len(parts); i++ {
+		dir := filepath.Join(append([]string{d.root}, parts[:i]...)...)
+
+		e, ok := d.dirs[dir]
+		if !ok {
+			e = &duEntry{dir: dir}
+			d.dirs[dir] = e
+		}
+
+		e.size += info.Size()
+		e.files++
+	}
+
+	return nil
+}
+
+// entries returns the directories sorted by size, largest first, keeping
+// only the first top ones if top is greater than zero
+func (d *duSummary) entries(top int) []duEntry {
+	entries := make([]duEntry, 0, len(d.dirs))
+	for _, e := range d.dirs {
+		entries = append(entries, *e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size != entries[j].size {
+			return entries[i].size > entries[j].size
+		}
+		return entries[i].dir < entries[j].dir
+	})
+
+	if top > 0 && len(entries) > top {
+		entries = entries[:top]
+	}
+
+	return entries
+}
+
+func (d *duSummary) print(out io.Writer, top int) error {
+	for _, e := range d.entries(top) {
+		if _, err := fmt.Fprintf(out, "%8s %8d  %s\n", humanSize(e.size), e.files, e.dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// humanSize formats n bytes using binary units, like 512B, 1.5K or 3.2G
+func humanSize(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHumanSize(t *testing.T) {
+	testCases := []struct {
+		size int64
+		exp  string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{5 * 1024 * 1024, "5.0M"},
+		{3 << 30, "3.0G"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.exp, func(t *testing.T) {
+			if res := humanSize(tc.size); res != tc.exp {
+				t.Errorf("Expected %q, got %q instead", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestRunDU(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]int{
+		"top.log":         100,
+		"a/one.log":       1000,
+		"a/deep/two.log":  2000,
+		"a/deep/skip.txt": 5000,
+		"b/three.log":     500,
+	}
+
+	for name, size := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		depth int
+		top   int
+		exp   string
+	}{
+		{name: "Depth1", depth: 1,
+			exp: "    3.5K        4  " + root + "\n" +
+				"    2.9K        2  " + filepath.Join(root, "a") + "\n" +
+				"    500B        1  " + filepath.Join(root, "b") + "\n"},
+		{name: "Depth0", depth: 0,
+			exp: "    3.5K        4  " + root + "\n"},
+		{name: "Unlimited", depth: -1,
+			exp: "    3.5K        4  " + root + "\n" +
+				"    2.9K        2  " + filepath.Join(root, "a") + "\n" +
+				"    2.0K        1  " + filepath.Join(root, "a", "deep") + "\n" +
+				"    500B        1  " + filepath.Join(root, "b") + "\n"},
+		{name: "Top2", depth: -1, top: 2,
+			exp: "    3.5K        4  " + root + "\n" +
+				"    2.9K        2  " + filepath.Join(root, "a") + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			cfg := config{ext: ".log", du: true, depth: tc.depth, top: tc.top}
+			if err := run(root, &buf, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tc.exp {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.exp, buf.String())
+			}
+		})
+	}
+}
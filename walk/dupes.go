@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// partialSize is the number of bytes hashed to split files of the same size
+// before hashing their whole content
+const partialSize = 4096
+
+// fileRef represents a file matched while looking for duplicates
+type fileRef struct {
+	path string
+	info fs.FileInfo
+}
+
+// findDupes groups files with identical content. Files are grouped by size
+// first, then by the hash of their first bytes and finally by the SHA-256
+// of their whole content, so only likely duplicates are read entirely.
+// Every set is sorted by path, and the sets by their first path
+func findDupes(files []fileRef) ([][]fileRef, error) {
+	bySize := map[int64][]fileRef{}
+	for _, f := range files {
+		// empty files have nothing to reclaim
+		if f.info.Size() > 0 {
+			bySize[f.info.Size()] = append(bySize[f.info.Size()], f)
+		}
+	}
+
+	var sets [][]fileRef
+
+	for _, group := range bySize {
+		group = dropHardLinks(group)
+		if len(group) < 2 {
+			continue
+		}
+
+		byPartial, err := groupBy(group, func(path string) (string, error) {
+			return hashFile(path, partialSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidates := range byPartial {
+			if len(candidates) < 2 {
+				continue
+			}
+
+			byFull, err := groupBy(candidates, func(path string) (string, error) {
+				return hashFile(path, -1)
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, set := range byFull {
+				if len(set) > 1 {
+					sets = append(sets, set)
+				}
+			}
+		}
+	}
+
+	for _, set := range sets {
+		sort.Slice(set, func(i, j int) bool {
+			return set[i].path < set[j].path
+		})
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i][0].path < sets[j][0].path
+	})
+
+	return sets, nil
+}
+
+// groupBy splits files by the key returned by keyFn for each path
+func groupBy(files []fileRef, keyFn func(string) (string, error)) (map[string][]fileRef, error) {
+	groups := map[string][]fileRef{}
+
+	for _, f := range files {
+		key, err := keyFn(f.path)
+		if err != nil {
+			return nil, err
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	return groups, nil
+}
+
+// dropHardLinks keeps a single path for files sharing the same inode, since
+// removing a hard link doesn't free any space
+func dropHardLinks(files []fileRef) []fileRef {
+	var unique []fileRef
+
+	for _, f := range files {
+		linked := false
+		for _, u := range unique {
+			if os.SameFile(f.info, u.info) {
+				linked = true
+				break
+			}
+		}
+
+		if !linked {
+			unique = append(unique, f)
+		}
+	}
+
+	return unique
+}
+
+// hashFile returns the SHA-256 of the first n bytes of path, or of the
+// whole file if n is negative
+func hashFile(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if n >= 0 {
+		r = io.LimitReader(f, n)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// keepFunc reports whether a should be kept over b
+type keepFunc func(a, b fileRef) bool
+
+// keepPolicy returns the keepFunc for the named policy
+func keepPolicy(name string) (keepFunc, error) {
+	switch name {
+	case "", "oldest":
+		return func(a, b fileRef) bool { return a.info.ModTime().Before(b.info.ModTime()) }, nil
+	case "newest":
+		return func(a, b fileRef) bool { return a.info.ModTime().After(b.info.ModTime()) }, nil
+	case "shortest":
+		return func(a, b fileRef) bool { return len(a.path) < len(b.path) }, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeep, name)
+	}
+}
+
+// reportDupes prints every duplicate set and the bytes wasted by the extra
+// copies. If cfg.del is set, all files but the one chosen by keep are
+// deleted, or just reported as such on a dry run
+func reportDupes(sets [][]fileRef, out io.Writer, cfg config, keep keepFunc, audit *auditLog) error {
+	var wasted int64
+
+	for _, set := range sets {
+		size := set[0].info.Size()
+		wasted += size * int64(len(set)-1)
+
+		fmt.Fprintf(out, "DUPLICATES: %d files, %d bytes each\n", len(set), size)
+
+		kept := 0
+		for i := range set {
+			if keep(set[i], set[kept]) {
+				kept = i
+			}
+		}
+
+		for i, f := range set {
+			switch {
+			case !cfg.del:
+				fmt.Fprintf(out, "  %s\n", f.path)
+			case i == kept:
+				fmt.Fprintf(out, "  KEEP %s\n", f.path)
+			case cfg.dryRun:
+				fmt.Fprintf(out, "  DELETE %s\n", f.path)
+			default:
+				err := audit.track("delete", f.path, func() (string, error) {
+					return delFile(f.path, cfg.trash)
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "  DELETED %s\n", f.path)
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "%d duplicate sets, %d bytes wasted\n", len(sets), wasted)
+	return err
+}
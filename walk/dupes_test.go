@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDupes creates files under a temp dir with the given content and
+// modification times, one hour apart in the order given
+func writeDupes(t *testing.T, files []struct{ name, data string }) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	base := time.Now().Add(-24 * time.Hour)
+
+	for i, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestFindDupes(t *testing.T) {
+	// same size, same first bytes, different tail
+	head := strings.Repeat("a", partialSize)
+
+	dir := writeDupes(t, []struct{ name, data string }{
+		{"a.txt", "hello"},
+		{"sub/b.txt", "hello"},
+		{"c.txt", "world"},
+		{"d.txt", head + "x"},
+		{"e.txt", head + "y"},
+		{"f.txt", ""},
+		{"g.txt", ""},
+	})
+
+	if err := os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "h.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []fileRef
+	err := walkTree(dir, walkOptions{}, func(path string, info os.FileInfo) error {
+		files = append(files, fileRef{path, info})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets, err := findDupes(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sets) != 1 {
+		t.Fatalf("Expected 1 duplicate set, got %d: %v", len(sets), sets)
+	}
+
+	if len(sets[0]) != 2 {
+		t.Fatalf("Expected 2 duplicates, got %d", len(sets[0]))
+	}
+
+	// a.txt and h.txt are hard links, only one of them is reported
+	if name := filepath.Base(sets[0][1].path); name != "b.txt" {
+		t.Errorf("Expected b.txt as duplicate, got %q", name)
+	}
+}
+
+func TestRunDupes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		keep    string
+		del     bool
+		dryRun  bool
+		expLeft []string
+		expOut  string
+		expErr  error
+	}{
+		{name: "Report", expLeft: []string{"a.txt", "bb.txt", "c.txt", "sub/a.txt"},
+			expOut: "1 duplicate sets, 10 bytes wasted\n"},
+		{name: "KeepOldest", keep: "oldest", del: true, expLeft: []string{"c.txt", "sub/a.txt"},
+			expOut: "KEEP"},
+		{name: "KeepNewest", keep: "newest", del: true, expLeft: []string{"bb.txt", "c.txt"},
+			expOut: "KEEP"},
+		{name: "KeepShortest", keep: "shortest", del: true, expLeft: []string{"a.txt", "c.txt"},
+			expOut: "KEEP"},
+		{name: "DryRun", keep: "shortest", del: true, dryRun: true,
+			expLeft: []string{"a.txt", "bb.txt", "c.txt", "sub/a.txt"}, expOut: "  DELETE"},
+		{name: "InvalidKeep", keep: "largest", expErr: ErrInvalidKeep},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeDupes(t, []struct{ name, data string }{
+				{"sub/a.txt", "hello"},
+				{"a.txt", "hello"},
+				{"bb.txt", "hello"},
+				{"c.txt", "other"},
+			})
+
+			var buf bytes.Buffer
+			cfg := config{dupes: true, keep: tc.keep, del: tc.del, dryRun: tc.dryRun, wLog: io.Discard}
+
+			err := run(dir, &buf, cfg)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(buf.String(), tc.expOut) {
+				t.Errorf("Expected output to contain %q, got %q", tc.expOut, buf.String())
+			}
+
+			var left []string
+			filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					rel, _ := filepath.Rel(dir, path)
+					left = append(left, filepath.ToSlash(rel))
+				}
+				return err
+			})
+
+			if strings.Join(left, ",") != strings.Join(tc.expLeft, ",") {
+				t.Errorf("Expected files %v, got %v", tc.expLeft, left)
+			}
+		})
+	}
+}
+
+func TestReportDupesLog(t *testing.T) {
+	dir := writeDupes(t, []struct{ name, data string }{
+		{"a.txt", "hello"},
+		{"b.txt", "hello"},
+	})
+
+	var files []fileRef
+	for _, name := range []string{"a.txt", "b.txt"} {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, fileRef{path, info})
+	}
+
+	sets, err := findDupes(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keep, _ := keepPolicy("oldest")
+
+	var out, logBuf bytes.Buffer
+	if err := reportDupes(sets, &out, config{del: true}, keep, newAuditLog(&logBuf)); err != nil {
+		t.Fatal(err)
+	}
+
+	var rec auditRecord
+	if err := json.Unmarshal(logBuf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Action != "delete" || rec.Path != filepath.Join(dir, "b.txt") {
+		t.Errorf("Expected b.txt deletion logged, got %+v", rec)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "errors"
+
+var (
+	ErrInvalidAge   = errors.New("Invalid age")
+	ErrInvalidMatch = errors.New("Invalid match mode")
+	ErrInvalidArchive = errors.New("Invalid archive")
+	ErrFileExists = errors.New("File already exists")
+	ErrInvalidKeep = errors.New("Invalid keep policy")
+	ErrInvalidTrash = errors.New("Invalid trash")
+	ErrInvalidTrashInfo = errors.New("Invalid trash info")
+	ErrPartial = errors.New("Some files failed")
+	ErrInvalidPolicy = errors.New("Invalid policy")
+)
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setFilters parses the filters given as text on the command line into c
+func (c *config) setFilters(olderThan, newerThan, pathRegex, owner, match string) error {
+	var err error
+
+	if c.olderThan, err = parseAge(olderThan); err != nil {
+		return err
+	}
+
+	if c.newerThan, err = parseAge(newerThan); err != nil {
+		return err
+	}
+
+	if pathRegex != "" {
+		if c.pathRe, err = regexp.Compile(pathRegex); err != nil {
+			return err
+		}
+	}
+
+	if c.owner, err = lookupOwner(owner); err != nil {
+		return err
+	}
+
+	switch match {
+	case "", "all":
+		c.anyMatch = false
+	case "any":
+		c.anyMatch = true
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidMatch, match)
+	}
+
+	return nil
+}
+
+// matchCriteria checks info against every criteria set in cfg. With
+// cfg.anyMatch a single matching criteria is enough, otherwise all of them
+// have to match. Files always match if no criteria is set
+func matchCriteria(path string, info os.FileInfo, cfg config) bool {
+	var results []bool
+
+	if cfg.ext != "" {
+		results = append(results, matchExt(path, cfg.ext))
+	}
+
+	if cfg.size > 0 {
+		results = append(results, info.Size() >= cfg.size)
+	}
+
+	if cfg.maxSize > 0 {
+		results = append(results, info.Size() <= cfg.maxSize)
+	}
+
+	age := time.Since(info.ModTime())
+
+	if cfg.olderThan > 0 {
+		results = append(results, age > cfg.olderThan)
+	}
+
+	if cfg.newerThan > 0 {
+		results = append(results, age < cfg.newerThan)
+	}
+
+	if len(cfg.names) > 0 {
+		results = append(results, matchGlobs(filepath.Base(path), cfg.names))
+	}
+
+	if cfg.pathRe != nil {
+		results = append(results, cfg.pathRe.MatchString(path))
+	}
+
+	if cfg.owner != "" {
+		uid, ok := fileOwner(info)
+		results = append(results, ok && uid == cfg.owner)
+	}
+
+	if len(results) == 0 {
+		return true
+	}
+
+	for _, r := range results {
+		if cfg.anyMatch && r {
+			return true
+		}
+
+		if !cfg.anyMatch && !r {
+			return false
+		}
+	}
+
+	return !cfg.anyMatch
+}
+
+// matchExt reports whether path has one of the comma separated extensions
+func matchExt(path, exts string) bool {
+	for _, ext := range strings.Split(exts, ",") {
+		if filepath.Ext(path) == strings.TrimSpace(ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlobs reports whether name matches any of the shell patterns
+func matchGlobs(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excludeDirs returns a function skipping directories whose name matches
+// any of the patterns, or nil if there are no patterns
+func excludeDirs(patterns []string) func(string) bool {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return func(path string) bool {
+		return matchGlobs(filepath.Base(path), patterns)
+	}
+}
+
+// splitList splits a comma separated flag value ignoring empty items
+func splitList(s string) []string {
+	var items []string
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseAge parses a duration accepting days (d) and weeks (w) on top of
+// the units supported by time.ParseDuration, like 30d or 2w
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidAge, s)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidAge, s)
+	}
+
+	return d, nil
+}
+
+// lookupOwner resolves a user name or numeric id into the id files are
+// compared against
+func lookupOwner(owner string) (string, error) {
+	if owner == "" {
+		return "", nil
+	}
+
+	if _, err := strconv.Atoi(owner); err == nil {
+		return owner, nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return "", err
+	}
+
+	return u.Uid, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchCriteria(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, nil)
+	defer cleanup()
+
+	// old.log is 40 days old and 5 bytes, new.txt is brand new and 11 bytes
+	oldFile := filepath.Join(tempDir, "old.log")
+	newFile := filepath.Join(tempDir, "new.txt")
+
+	if err := os.WriteFile(oldFile, []byte("dummy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(newFile, []byte("dummy dummy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		cfg    config
+		expOld bool
+		expNew bool
+	}{
+		{name: "NoFilter", cfg: config{}, expOld: true, expNew: true},
+		{name: "MultipleExtensions", cfg: config{ext: ".log,.txt"}, expOld: true, expNew: true},
+		{name: "SingleExtension", cfg: config{ext: ".txt"}, expOld: false, expNew: true},
+		{name: "MaxSize", cfg: config{maxSize: 10}, expOld: true, expNew: false},
+		{name: "MinMaxSize", cfg: config{size: 6, maxSize: 20}, expOld: false, expNew: true},
+		{name: "OlderThan", cfg: config{olderThan: 30 * 24 * time.Hour}, expOld: true, expNew: false},
+		{name: "NewerThan", cfg: config{newerThan: time.Hour}, expOld: false, expNew: true},
+		{name: "NameGlob", cfg: config{names: []string{"old.*"}}, expOld: true, expNew: false},
+		{name: "PathRegex", cfg: config{pathRe: regexp.MustCompile(`new\.txt$`)}, expOld: false, expNew: true},
+		{name: "Owner", cfg: config{owner: u.Uid}, expOld: true, expNew: true},
+		{name: "OtherOwner", cfg: config{owner: "999999"}, expOld: false, expNew: false},
+		{name: "AllMatch", cfg: config{ext: ".log", newerThan: time.Hour}, expOld: false, expNew: false},
+		{name: "AnyMatch", cfg: config{ext: ".log", newerThan: time.Hour, anyMatch: true}, expOld: true, expNew: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for file, exp := range map[string]bool{oldFile: tc.expOld, newFile: tc.expNew} {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if res := matchCriteria(file, info, tc.cfg); res != exp {
+					t.Errorf("Expected %t for %s, got %t instead", exp, filepath.Base(file), res)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	testCases := []struct {
+		age    string
+		exp    time.Duration
+		expErr error
+	}{
+		{age: "", exp: 0},
+		{age: "30d", exp: 30 * 24 * time.Hour},
+		{age: "2w", exp: 14 * 24 * time.Hour},
+		{age: "1.5d", exp: 36 * time.Hour},
+		{age: "90m", exp: 90 * time.Minute},
+		{age: "xd", expErr: ErrInvalidAge},
+		{age: "-1h", expErr: ErrInvalidAge},
+		{age: "soon", expErr: ErrInvalidAge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.age, func(t *testing.T) {
+			res, err := parseAge(tc.age)
+
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			if res != tc.exp {
+				t.Errorf("Expected %s, got %s instead", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestSetFiltersInvalid(t *testing.T) {
+	var c config
+
+	if err := c.setFilters("", "", "", "", "some"); !errors.Is(err, ErrInvalidMatch) {
+		t.Errorf("Expected error %q, got %q instead", ErrInvalidMatch, err)
+	}
+
+	if err := c.setFilters("", "", "[", "", "all"); err == nil {
+		t.Error("Expected error for invalid regex, got nil instead")
+	}
+}
+
+func TestRunExcludeDirs(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 1})
+	defer cleanup()
+
+	for _, dir := range []string{"node_modules", "cache.tmp", "src"} {
+		path := filepath.Join(tempDir, dir)
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(path, "file.log"), []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	cfg := config{list: true, ordered: true, exclude: []string{"node_modules", "*.tmp"}}
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		filepath.Join(tempDir, "file1.log"),
+		filepath.Join(tempDir, "src", "file.log"),
+	}
+
+	if res := strings.Fields(buffer.String()); strings.Join(res, " ") != strings.Join(exp, " ") {
+		t.Errorf("Expected %q, got %q instead", exp, res)
+	}
+}
@@ -1,3 +1,9 @@
 module pragprog.com/rggo/fileSystem/walk
 
 go 1.20
+
+require (
+	github.com/klauspost/compress v1.17.9
+	github.com/pelletier/go-toml/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single pattern read from an ignore file
+type ignoreRule struct {
+	base     string   // directory holding the ignore file
+	segments []string // pattern split on slashes
+	anchored bool     // pattern is relative to base instead of matching any name
+	dirOnly  bool     // pattern only matches directories
+	negate   bool     // pattern re-includes a previously ignored path
+}
+
+// ignoreList holds the rules found in a directory and its parents, in the
+// order they're applied. It's never modified once created, so it can be
+// shared by the subdirectories read concurrently
+type ignoreList struct {
+	rules []ignoreRule
+}
+
+// loadIgnore returns the rules in parent followed by the rules read from the
+// ignore file name in dir, if it exists, using the .gitignore syntax: blank
+// lines and lines starting with # are skipped, ! negates a pattern, a
+// trailing slash only matches directories, and patterns containing a slash
+// are relative to dir while the others match names at any depth
+func loadIgnore(parent *ignoreList, dir, name string) (*ignoreList, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return parent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{base: dir}
+
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		r.segments = strings.Split(line, "/")
+		rules = append(rules, r)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return parent, nil
+	}
+
+	if parent != nil {
+		rules = append(append([]ignoreRule{}, parent.rules...), rules...)
+	}
+
+	return &ignoreList{rules: rules}, nil
+}
+
+// match reports whether path is ignored. The last matching rule wins
+func (l *ignoreList) match(path string, isDir bool) bool {
+	if l == nil {
+		return false
+	}
+
+	ignored := false
+
+	for _, r := range l.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		if r.match(path) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+// match reports whether path matches r. Rules only apply to paths under
+// the directory holding their ignore file
+func (r ignoreRule) match(path string) bool {
+	rel, err := filepath.Rel(r.base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	if !r.anchored {
+		ok, _ := filepath.Match(r.segments[0], filepath.Base(path))
+		return ok
+	}
+
+	return matchSegments(r.segments, strings.Split(filepath.ToSlash(rel), "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where a **
+// segment matches any number of path segments
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], path[1:])
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreList(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rootRules := "# comment\n\n*.tmp\nbuild/\n/top.log\ndocs/**/*.md\n"
+	subRules := "!keep.tmp\nlocal.txt\n"
+
+	for dir, rules := range map[string]string{root: rootRules, sub: subRules} {
+		if err := os.WriteFile(filepath.Join(dir, ".walkignore"), []byte(rules), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parent, err := loadIgnore(nil, root, ".walkignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child, err := loadIgnore(parent, sub, ".walkignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name  string
+		list  *ignoreList
+		path  string
+		isDir bool
+		exp   bool
+	}{
+		{"NameAnyDepth", child, "sub/a/b.tmp", false, true},
+		{"NoMatch", parent, "a.log", false, false},
+		{"DirOnly", parent, "x/build", true, true},
+		{"DirOnlyFile", parent, "x/build", false, false},
+		{"Anchored", parent, "top.log", false, true},
+		{"AnchoredDeeper", parent, "sub/top.log", false, false},
+		{"DoubleStar", parent, "docs/a/b/c.md", false, true},
+		{"DoubleStarNone", parent, "docs/c.md", false, true},
+		{"Negated", child, "sub/keep.tmp", false, false},
+		{"NegatedOutside", child, "keep.tmp", false, true},
+		{"ChildRule", child, "sub/local.txt", false, true},
+		{"ChildRuleParent", parent, "sub/local.txt", false, false},
+		{"NilList", nil, "a.tmp", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(root, filepath.FromSlash(tc.path))
+
+			if res := tc.list.match(path, tc.isDir); res != tc.exp {
+				t.Errorf("Expected %t, got %t instead", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreMissing(t *testing.T) {
+	parent := &ignoreList{}
+
+	l, err := loadIgnore(parent, t.TempDir(), ".walkignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l != parent {
+		t.Error("Expected parent rules without an ignore file")
+	}
+}
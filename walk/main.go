@@ -1,41 +1,106 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"flag"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"log"
+	"regexp"
+	"runtime"
+	"time"
 )
 
 type config struct {
-	ext string // extension to filter out
+	ext string // extensions to filter out, comma separated
 	size int64 // min file size
+	maxSize int64 // max file size
+	olderThan time.Duration // min modification age
+	newerThan time.Duration // max modification age
+	names []string // file name globs
+	pathRe *regexp.Regexp // path regular expression
+	owner string // owner user id
+	exclude []string // directory name globs to skip
+	anyMatch bool // match files passing any filter instead of all
 	list bool // list files
 	del bool // delete files
-	wLog io.Writer	// log destination writer
+	wLog io.Writer	// audit log destination writer, nil to disable it
 	archive string // archive directory
+	archiveFile string // single archive file: .tar.gz, .tar.zst or .zip
+	workers int // number of directories read concurrently
+	ordered bool // process files in lexical order
+	followLinks bool // follow symbolic links
+	specials bool // include sockets, devices and named pipes
+	xdev bool // stay on the filesystem holding root
+	ignoreFile string // name of .gitignore style files honored in each directory
+	keepGoing bool // record errors and keep going instead of stopping at the first one
+	stats *policyStats // counts the files matched, if set
+	dryRun bool // report archive and delete actions without executing them
+	restore string // archive directory to restore files from
+	force bool // overwrite existing files when restoring
+	dupes bool // report duplicate files
+	keep string // duplicate to keep when deleting: oldest, newest or shortest
+	trash *trashCan // move deleted files here instead of removing them
+	purge bool // remove trashed files older than retention
+	retention time.Duration // how long trashed files are kept
+	du bool // report disk usage per directory
+	depth int // directory levels reported by du, negative for all
+	top int // largest directories reported by du, 0 for all
 }
 
 func main() {
 	// parsing command line flags
 	root := flag.String("root", ".",  "Root directory to start")
-	logFile := flag.String("log", "", "Log deletes to this file")
+	policyFile := flag.String("policy", "", "Run the policies in this YAML or TOML file instead of the action flags")
+	logFile := flag.String("log", "", "Write a JSON lines audit log of every action to this file")
 
 	// action options
 	list := flag.Bool("list", false, "List files only")
 	del := flag.Bool("del", false, "Delete files")
 	archive := flag.String("archive", "", "Archive directory")
+	archiveFile := flag.String("archive-file", "", "Archive all files into a single .tar.gz, .tar.zst or .zip")
+	dryRun := flag.Bool("dry-run", false, "Show what would be archived or deleted without doing it")
+	restore := flag.String("restore", "", "Restore files archived in this directory into root")
+	force := flag.Bool("force", false, "Overwrite existing files when restoring")
+	dupes := flag.Bool("dupes", false, "Report duplicate files, deleting all but one copy with -del")
+	keep := flag.String("keep", "oldest", "Duplicate to keep with -dupes -del: oldest, newest or shortest")
+	trash := flag.Bool("trash", false, "Move deleted files to the user trash instead of removing them")
+	quarantine := flag.String("quarantine", "", "Move deleted files to this directory instead of removing them")
+	purge := flag.Bool("purge", false, "Remove files quarantined longer than -retention ago")
+	retention := flag.String("retention", "30d", "How long trashed files are kept before -purge removes them")
+
+	// disk usage options
+	du := flag.Bool("du", false, "Report the size of matched files per directory")
+	depth := flag.Int("depth", 1, "Directory levels below root reported by -du, negative for all")
+	top := flag.Int("top", 0, "Report only the N largest directories with -du")
 
 	// filter options
-	ext := flag.String("ext", "", "File extension to filter out")
+	ext := flag.String("ext", "", "File extensions to filter out, comma separated")
 	size := flag.Int64("size", 0, "Minimum file size")
+	maxSize := flag.Int64("max-size", 0, "Maximum file size")
+	olderThan := flag.String("older-than", "", "Minimum modification age, like 30d or 12h")
+	newerThan := flag.String("newer-than", "", "Maximum modification age, like 7d or 30m")
+	names := flag.String("name", "", "File name globs, comma separated")
+	pathRegex := flag.String("path-regex", "", "Regular expression matching the file path")
+	owner := flag.String("owner", "", "File owner name or id")
+	exclude := flag.String("exclude", "", "Directory name globs to skip, comma separated")
+	match := flag.String("match", "all", "Match files passing all filters or any of them")
+
+	// traversal options
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of directories read concurrently")
+	ordered := flag.Bool("ordered", false, "Process files in lexical order (keeps all paths in memory)")
+	follow := flag.Bool("follow", false, "Follow symbolic links, reading linked directories once")
+	specials := flag.Bool("specials", false, "Include sockets, devices and named pipes")
+	xdev := flag.Bool("xdev", false, "Don't descend into directories on other filesystems")
+	keepGoing := flag.Bool("keep-going", false, "Keep going after errors, reporting them at the end")
+	ignoreFile := flag.String("ignore-file", "", "Skip paths listed in files with this name, like .gitignore, in each directory")
 
 	flag.Parse()
 
 	var (
-		f = os.Stdout
+		wLog io.Writer
 	)
 
 	if *logFile != "" {
@@ -45,54 +110,278 @@ func main() {
 			os.Exit(1)
 		}
 		defer f.Close()
+		wLog = f
 	}
 
 	c := config {
 		ext : *ext,
 		size : *size,
+		maxSize: *maxSize,
+		names: splitList(*names),
+		exclude: splitList(*exclude),
 		list : *list,
 		del : *del,
-		wLog: f,
+		wLog: wLog,
 		archive: *archive,
+		archiveFile: *archiveFile,
+		workers: *workers,
+		ordered: *ordered,
+		followLinks: *follow,
+		specials: *specials,
+		xdev: *xdev,
+		ignoreFile: *ignoreFile,
+		keepGoing: *keepGoing,
+		dryRun: *dryRun,
+		restore: *restore,
+		force: *force,
+		dupes: *dupes,
+		keep: *keep,
+		du: *du,
+		depth: *depth,
+		top: *top,
+	}
+
+	if err := c.setFilters(*olderThan, *newerThan, *pathRegex, *owner, *match); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if err := run(*root, os.Stdout, c); err != nil {
+	if err := c.setTrash(*trash, *quarantine, *purge, *retention); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+
+	var err error
+
+	if *policyFile != "" {
+		var policies []policy
+		if policies, err = loadPolicies(*policyFile); err == nil {
+			err = runPolicies(policies, os.Stdout, c)
+		}
+	} else {
+		err = run(*root, os.Stdout, c)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		// some files were processed, tell scripts apart from a failed run
+		if errors.Is(err, ErrPartial) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
 }
 
 func run(root string, out io.Writer, cfg config) error {
-	delLogger := log.New(cfg.wLog, "DELETED FILE: ", log.LstdFlags)
+	audit := newAuditLog(cfg.wLog)
+
+	opts := walkOptions{
+		workers: cfg.workers,
+		ordered: cfg.ordered,
+		skipDir: excludeDirs(cfg.exclude),
+		followLinks: cfg.followLinks,
+		xdev: cfg.xdev,
+		ignoreFile: cfg.ignoreFile,
+		keepGoing: cfg.keepGoing,
+	}
+
+	if cfg.restore != "" {
+		return walkTree(cfg.restore, opts, func (path string, info fs.FileInfo) error {
+			if filepath.Ext(path) != ".gz" {
+				return nil
+			}
 
-	return filepath.Walk(root, 
-		func (path string, info os.FileInfo, err error) error {
+			var target string
+			err := audit.track("restore", path, func () (string, error) {
+				var err error
+				target, err = restoreFile(cfg.restore, root, path, cfg.force)
+				return target, err
+			})
 			if err != nil {
 				return err
 			}
 
-			if filterOut(path, cfg.ext, cfg.size, info) {
+			return listFile(target, out)
+		})
+	}
+
+	if cfg.purge {
+		return purgeTrash(cfg, out, audit)
+	}
+
+	if cfg.dupes {
+		keep, err := keepPolicy(cfg.keep)
+		if err != nil {
+			return err
+		}
+
+		var files []fileRef
+		walkErr := walkTree(root, opts, func (path string, info fs.FileInfo) error {
+			if !filterOut(path, info, cfg) {
+				files = append(files, fileRef{path, info})
+			}
+			return nil
+		})
+		if walkErr != nil && !errors.Is(walkErr, ErrPartial) {
+			return walkErr
+		}
+
+		sets, err := findDupes(files)
+		if err != nil {
+			return err
+		}
+
+		if err := reportDupes(sets, out, cfg, keep, audit); err != nil {
+			return err
+		}
+
+		return walkErr
+	}
+
+	if cfg.du {
+		d := newDUSummary(root, cfg.depth)
+
+		walkErr := walkTree(root, opts, func (path string, info fs.FileInfo) error {
+			if filterOut(path, info, cfg) {
 				return nil
 			}
+			return d.add(path, info)
+		})
+		if walkErr != nil && !errors.Is(walkErr, ErrPartial) {
+			return walkErr
+		}
+
+		if err := d.print(out, cfg.top); err != nil {
+			return err
+		}
+
+		return walkErr
+	}
+
+	if cfg.archive != "" && cfg.archiveFile != "" {
+		return fmt.Errorf("%w: -archive and -archive-file are mutually exclusive", ErrInvalidArchive)
+	}
+
+	// dry run only applies when there's something to archive or delete
+	archiving := cfg.archive != "" || cfg.archiveFile != ""
+	dryRun := cfg.dryRun && !cfg.list && (archiving || cfg.del)
+	var sum dryRunSummary
+
+	if dryRun && cfg.archive != "" {
+		if err := checkDir(cfg.archive); err != nil {
+			return err
+		}
+	}
+
+	var b *bundle
+	var bundled []string // files to delete once the archive file is complete
+	if cfg.archiveFile != "" && !cfg.list && !dryRun {
+		// an existing archive may hold the only copy of files already deleted
+		if _, err := os.Stat(cfg.archiveFile); cfg.del && err == nil {
+			return fmt.Errorf("%w: %s", ErrFileExists, cfg.archiveFile)
+		}
+
+		var err error
+		if b, err = newBundle(cfg.archiveFile, root); err != nil {
+			return err
+		}
+	}
+
+	err := walkTree(root, opts,
+		func (path string, info fs.FileInfo) error {
+			if filterOut(path, info, cfg) {
+				return nil
+			}
+
+			if cfg.stats != nil {
+				cfg.stats.files++
+				cfg.stats.bytes += info.Size()
+			}
 
 			// list was explicitly set, don't do anything else
 			if cfg.list {
-				return listFile(path, out)
+				return audit.track("list", path, func () (string, error) {
+					return "", listFile(path, out)
+				})
+			}
+
+			if dryRun {
+				return dryRunFile(root, path, info, cfg, &sum, out)
+			}
+
+			if b != nil {
+				if b.owns(path) {
+					return nil
+				}
+
+				err := audit.track("archive", path, func () (string, error) {
+					name, err := b.name(path)
+					if err != nil {
+						return "", err
+					}
+					return cfg.archiveFile + ":" + name, b.add(path, info)
+				})
+				if err != nil {
+					return err
+				}
+
+				if cfg.del {
+					bundled = append(bundled, path)
+					return nil
+				}
 			}
 
 			// Archive files and continue if successful
 			if cfg.archive != "" {
-				if err := archiveFile(cfg.archive, root, path); err != nil {
+				err := audit.track("archive", path, func () (string, error) {
+					target, err := archivePath(cfg.archive, root, path)
+					if err != nil {
+						return "", err
+					}
+					return target, archiveFile(cfg.archive, root, path)
+				})
+				if err != nil {
 					return err
 				}
 			}
 
 			// delete files
 			if cfg.del {
-				return delFile(path, delLogger)
+				return audit.track("delete", path, func () (string, error) {
+					return delFile(path, cfg.trash)
+				})
 			}
 
 			// list is the default option if nothing else was set
-			return listFile(path, out)
-		})	
+			return audit.track("list", path, func () (string, error) {
+				return "", listFile(path, out)
+			})
+		})
+
+	if b != nil {
+		cerr := b.Close()
+		if err == nil {
+			err = cerr
+		}
+
+		// the originals are only deleted once the archive is complete, so
+		// they aren't lost if it can't be finished
+		if cerr == nil && (err == nil || errors.Is(err, ErrPartial)) {
+			err = delBundled(bundled, cfg, audit, err)
+		}
+	}
+
+	// with -keep-going, report what was done before the errors
+	if err != nil && !errors.Is(err, ErrPartial) {
+		return err
+	}
+
+	if dryRun {
+		if perr := sum.print(out); perr != nil {
+			return perr
+		}
+	}
+
+	return err
 }
\ No newline at end of file
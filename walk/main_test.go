@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 	"strings"
+	"time"
 )
 
 func TestMain(t *testing.T) {
@@ -163,6 +166,141 @@ func TestRunArchive(t *testing.T) {
 }
 
 
+func TestRunDryRun(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3, ".gz": 2})
+	defer cleanup()
+
+	archiveDir, cleanupArchive := createTempDir(t, nil)
+	defer cleanupArchive()
+
+	var (
+		buffer bytes.Buffer
+		logBuffer bytes.Buffer
+	)
+
+	cfg := config{ext: ".log", del: true, archive: archiveDir, dryRun: true, ordered: true, wLog: &logBuffer}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var expOut strings.Builder
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("file%d.log", i))
+		fmt.Fprintf(&expOut, "ARCHIVE %s -> %s\n", path, filepath.Join(archiveDir, fmt.Sprintf("file%d.log.gz", i)))
+		fmt.Fprintf(&expOut, "DELETE %s\n", path)
+	}
+	expOut.WriteString("DRY RUN: 3 files, 15 bytes matched, 15 bytes would be freed\n")
+
+	if res := buffer.String(); res != expOut.String() {
+		t.Errorf("Expected %q, got %q instead\n", expOut.String(), res)
+	}
+
+	filesLeft, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filesLeft) != 5 {
+		t.Errorf("Expected 5 files left, got %d instead\n", len(filesLeft))
+	}
+
+	filesArchived, err := os.ReadDir(archiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filesArchived) != 0 {
+		t.Errorf("Expected no files archived, got %d instead\n", len(filesArchived))
+	}
+
+	if logBuffer.Len() != 0 {
+		t.Errorf("Expected no deletes logged, got %q instead\n", logBuffer.String())
+	}
+}
+
+
+func TestRunRestore(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3})
+	defer cleanup()
+
+	archiveDir, cleanupArchive := createTempDir(t, nil)
+	defer cleanupArchive()
+
+	subDir := filepath.Join(tempDir, "sub")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(subDir, "nested.log"), []byte("nested"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	for _, f := range []string{"file1.log", "sub/nested.log"} {
+		if err := os.Chtimes(filepath.Join(tempDir, f), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// archive and delete everything, then restore into a new root
+	if err := run(tempDir, io.Discard, config{archive: archiveDir, del: true, wLog: io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+
+	restoreDir, cleanupRestore := createTempDir(t, nil)
+	defer cleanupRestore()
+
+	var buffer bytes.Buffer
+	if err := run(restoreDir, &buffer, config{restore: archiveDir, ordered: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expOut := strings.Join([]string{
+		filepath.Join(restoreDir, "file1.log"),
+		filepath.Join(restoreDir, "file2.log"),
+		filepath.Join(restoreDir, "file3.log"),
+		filepath.Join(restoreDir, "sub", "nested.log"),
+	}, "\n") + "\n"
+
+	if res := buffer.String(); res != expOut {
+		t.Errorf("Expected %q, got %q instead\n", expOut, res)
+	}
+
+	for f, exp := range map[string]string{"file1.log": "dummy", "sub/nested.log": "nested"} {
+		path := filepath.Join(restoreDir, f)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != exp {
+			t.Errorf("Expected %q, got %q instead\n", exp, data)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("Expected mtime %v for %s, got %v instead\n", mtime, f, info.ModTime())
+		}
+	}
+
+	// restoring again must not overwrite the files unless forced
+	err := run(restoreDir, io.Discard, config{restore: archiveDir})
+	if !errors.Is(err, ErrFileExists) {
+		t.Errorf("Expected error %q, got %q instead\n", ErrFileExists, err)
+	}
+
+	if err := run(restoreDir, io.Discard, config{restore: archiveDir, force: true}); err != nil {
+		t.Errorf("Unexpected error: %q\n", err)
+	}
+}
+
+
 func createTempDir(t *testing.T, files map[string]int) (dirname string, cleanup func()) {
 	// marks this function as a helper method
 	t.Helper()
@@ -186,3 +324,45 @@ func createTempDir(t *testing.T, files map[string]int) (dirname string, cleanup
 	return tempDir, func(){os.RemoveAll(tempDir)}
 }
 
+
+func TestRunKeepGoing(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3})
+	defer cleanup()
+
+	archiveDir := t.TempDir()
+
+	// a directory in place of the first archive makes archiving it fail
+	if err := os.Mkdir(filepath.Join(archiveDir, "file1.log.gz"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		keepGoing bool
+	}{
+		{name: "Stop", keepGoing: false},
+		{name: "KeepGoing", keepGoing: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config{archive: archiveDir, ordered: true, keepGoing: tc.keepGoing}
+
+			var buffer bytes.Buffer
+			err := run(tempDir, &buffer, cfg)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if errors.Is(err, ErrPartial) != tc.keepGoing {
+				t.Errorf("Expected partial failure %t, got %q", tc.keepGoing, err)
+			}
+
+			// files after the failing one are only processed when keeping going
+			listed := strings.Count(buffer.String(), "\n")
+			if exp := map[bool]int{false: 0, true: 2}[tc.keepGoing]; listed != exp {
+				t.Errorf("Expected %d files processed, got %d: %q", exp, listed, buffer.String())
+			}
+		})
+	}
+}
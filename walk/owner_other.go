@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileOwner isn't supported on this platform, so no file matches an owner
+func fileOwner(info os.FileInfo) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the format of the file given to -policy, in YAML or TOML
+type policyFile struct {
+	Policies []policy `yaml:"policies" toml:"policies"`
+}
+
+// policy applies actions to the files matching its filters under roots.
+// Filters use the same syntax as the command line flags with the same name
+type policy struct {
+	Name        string   `yaml:"name" toml:"name"`
+	Roots       []string `yaml:"roots" toml:"roots"`
+	Actions     []string `yaml:"actions" toml:"actions"` // list, archive or delete
+	Archive     string   `yaml:"archive" toml:"archive"`
+	ArchiveFile string   `yaml:"archive_file" toml:"archive_file"`
+	Ext         string   `yaml:"ext" toml:"ext"`
+	Size        int64    `yaml:"size" toml:"size"`
+	MaxSize     int64    `yaml:"max_size" toml:"max_size"`
+	OlderThan   string   `yaml:"older_than" toml:"older_than"`
+	NewerThan   string   `yaml:"newer_than" toml:"newer_than"`
+	Names       []string `yaml:"names" toml:"names"`
+	PathRegex   string   `yaml:"path_regex" toml:"path_regex"`
+	Owner       string   `yaml:"owner" toml:"owner"`
+	Exclude     []string `yaml:"exclude" toml:"exclude"`
+	Match       string   `yaml:"match" toml:"match"`
+}
+
+// policyStats counts the files matched by a policy
+type policyStats struct {
+	files int
+	bytes int64
+}
+
+// loadPolicies reads the policies in fname, choosing the format by its
+// extension. Unknown keys are rejected to catch typos in the file
+func loadPolicies(fname string) ([]policy, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var pf policyFile
+
+	switch filepath.Ext(fname) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&pf)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(&pf)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %s", ErrInvalidPolicy, fname)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidPolicy, fname, err)
+	}
+
+	if len(pf.Policies) == 0 {
+		return nil, fmt.Errorf("%w: %s: no policies", ErrInvalidPolicy, fname)
+	}
+
+	for i := range pf.Policies {
+		if pf.Policies[i].Name == "" {
+			pf.Policies[i].Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+
+	return pf.Policies, nil
+}
+
+// config returns the configuration running p, taking the options not
+// related to filters and actions, like the number of workers, from base
+func (p policy) config(base config) (config, error) {
+	c := config{
+		ext:         p.Ext,
+		size:        p.Size,
+		maxSize:     p.MaxSize,
+		names:       p.Names,
+		exclude:     p.Exclude,
+		archive:     p.Archive,
+		archiveFile: p.ArchiveFile,
+		wLog:        base.wLog,
+		workers:     base.workers,
+		ordered:     base.ordered,
+		dryRun:      base.dryRun,
+		trash:       base.trash,
+		followLinks: base.followLinks,
+		specials:    base.specials,
+		xdev:        base.xdev,
+		ignoreFile:  base.ignoreFile,
+		keepGoing:   base.keepGoing,
+	}
+
+	if len(p.Roots) == 0 {
+		return c, fmt.Errorf("%w: %s: no roots", ErrInvalidPolicy, p.Name)
+	}
+
+	archiving := false
+
+	for _, action := range p.Actions {
+		switch action {
+		case "list":
+			c.list = true
+		case "archive":
+			archiving = true
+		case "delete":
+			c.del = true
+		default:
+			return c, fmt.Errorf("%w: %s: unknown action %s", ErrInvalidPolicy, p.Name, action)
+		}
+	}
+
+	if len(p.Actions) == 0 {
+		return c, fmt.Errorf("%w: %s: no actions", ErrInvalidPolicy, p.Name)
+	}
+
+	// listing skips every other action, which would silently do nothing
+	if c.list && len(p.Actions) > 1 {
+		return c, fmt.Errorf("%w: %s: list cannot be combined with other actions", ErrInvalidPolicy, p.Name)
+	}
+
+	if archiving != (p.Archive != "" || p.ArchiveFile != "") {
+		return c, fmt.Errorf("%w: %s: the archive action requires archive or archive_file", ErrInvalidPolicy, p.Name)
+	}
+
+	if p.ArchiveFile != "" {
+		// every root would recreate the same archive, losing the files
+		// bundled from the previous ones
+		if len(p.Roots) > 1 {
+			return c, fmt.Errorf("%w: %s: archive_file requires a single root", ErrInvalidPolicy, p.Name)
+		}
+
+		// an existing archive may hold the only copy of files already deleted
+		if _, err := os.Stat(p.ArchiveFile); c.del && err == nil {
+			return c, fmt.Errorf("%w: %s: %w: %s", ErrInvalidPolicy, p.Name, ErrFileExists, p.ArchiveFile)
+		}
+	}
+
+	if err := c.setFilters(p.OlderThan, p.NewerThan, p.PathRegex, p.Owner, p.Match); err != nil {
+		return c, fmt.Errorf("%w: %s: %w", ErrInvalidPolicy, p.Name, err)
+	}
+
+	return c, nil
+}
+
+// runPolicies runs every policy on each of its roots, reporting how many
+// files each one matched. All the policies are checked before running any,
+// and a failing policy doesn't stop the others
+func runPolicies(policies []policy, out io.Writer, base config) error {
+	cfgs := make([]config, len(policies))
+
+	for i, p := range policies {
+		var err error
+		if cfgs[i], err = p.config(base); err != nil {
+			return err
+		}
+	}
+
+	var errs walkErrors
+
+	for i, p := range policies {
+		for _, root := range p.Roots {
+			cfg := cfgs[i]
+			cfg.stats = &policyStats{}
+
+			fmt.Fprintf(out, "POLICY %s: %s\n", p.Name, root)
+
+			result := "ok"
+			if err := run(root, out, cfg); err != nil {
+				errs.errs = append(errs.errs, fmt.Errorf("policy %s: %s: %w", p.Name, root, err))
+				result = "failed"
+			}
+
+			fmt.Fprintf(out, "POLICY %s: %s: %d files, %d bytes, %s\n",
+				p.Name, root, cfg.stats.files, cfg.stats.bytes, result)
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writePolicy(t *testing.T, name, content string) string {
+	t.Helper()
+
+	fname := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fname
+}
+
+func TestLoadPolicies(t *testing.T) {
+	yamlPolicy := `policies:
+  - name: logs
+    roots: [/var/log/app]
+    ext: .log
+    older_than: 7d
+    actions: [archive]
+    archive: /backup
+  - roots: [/tmp/app]
+    older_than: 90d
+    actions: [delete]
+`
+	tomlPolicy := `[[policies]]
+name = "logs"
+roots = ["/var/log/app"]
+ext = ".log"
+older_than = "7d"
+actions = ["archive"]
+archive = "/backup"
+
+[[policies]]
+roots = ["/tmp/app"]
+older_than = "90d"
+actions = ["delete"]
+`
+
+	testCases := []struct {
+		name    string
+		fname   string
+		content string
+		expErr  error
+	}{
+		{name: "YAML", fname: "policy.yaml", content: yamlPolicy},
+		{name: "TOML", fname: "policy.toml", content: tomlPolicy},
+		{name: "UnknownKey", fname: "policy.yaml", content: "policies:\n  - roots: [a]\n    older: 7d\n", expErr: ErrInvalidPolicy},
+		{name: "Empty", fname: "policy.toml", content: "", expErr: ErrInvalidPolicy},
+		{name: "UnknownFormat", fname: "policy.json", content: "{}", expErr: ErrInvalidPolicy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policies, err := loadPolicies(writePolicy(t, tc.fname, tc.content))
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(policies) != 2 {
+				t.Fatalf("Expected 2 policies, got %d", len(policies))
+			}
+
+			p := policies[0]
+			if p.Name != "logs" || p.Ext != ".log" || p.OlderThan != "7d" || p.Archive != "/backup" {
+				t.Errorf("Unexpected policy %+v", p)
+			}
+
+			if policies[1].Name != "#2" {
+				t.Errorf("Expected default name #2, got %q", policies[1].Name)
+			}
+		})
+	}
+}
+
+func TestPolicyConfig(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "old.tar.gz")
+	if err := os.WriteFile(existing, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		p      policy
+		expErr error
+	}{
+		{name: "Valid", p: policy{Roots: []string{"a"}, Actions: []string{"archive", "delete"}, Archive: "b", OlderThan: "7d"}},
+		{name: "NoRoots", p: policy{Actions: []string{"list"}}, expErr: ErrInvalidPolicy},
+		{name: "NoActions", p: policy{Roots: []string{"a"}}, expErr: ErrInvalidPolicy},
+		{name: "UnknownAction", p: policy{Roots: []string{"a"}, Actions: []string{"shred"}}, expErr: ErrInvalidPolicy},
+		{name: "ArchiveNoDest", p: policy{Roots: []string{"a"}, Actions: []string{"archive"}}, expErr: ErrInvalidPolicy},
+		{name: "DestNoArchive", p: policy{Roots: []string{"a"}, Actions: []string{"list"}, Archive: "b"}, expErr: ErrInvalidPolicy},
+		{name: "ListDelete", p: policy{Roots: []string{"a"}, Actions: []string{"list", "delete"}}, expErr: ErrInvalidPolicy},
+		{name: "ListArchive", p: policy{Roots: []string{"a"}, Actions: []string{"list", "archive"}, Archive: "b"}, expErr: ErrInvalidPolicy},
+		{name: "ArchiveFileRoots", p: policy{Roots: []string{"a", "c"}, Actions: []string{"archive", "delete"}, ArchiveFile: "b.tar.gz"}, expErr: ErrInvalidPolicy},
+		{name: "ArchiveFileExists", p: policy{Roots: []string{"a"}, Actions: []string{"archive", "delete"}, ArchiveFile: existing}, expErr: ErrFileExists},
+		{name: "InvalidAge", p: policy{Roots: []string{"a"}, Actions: []string{"list"}, OlderThan: "soon"}, expErr: ErrInvalidAge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tc.p.config(config{workers: 3})
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cfg.del || cfg.archive != "b" || cfg.olderThan != 7*24*time.Hour || cfg.workers != 3 {
+				t.Errorf("Unexpected config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestRunPolicies(t *testing.T) {
+	root := t.TempDir()
+	archiveDir := t.TempDir()
+
+	ages := map[string]time.Duration{
+		"new.log": time.Hour,
+		"old.log": 10 * 24 * time.Hour,
+		"new.tmp": 30 * 24 * time.Hour,
+		"old.tmp": 100 * 24 * time.Hour,
+	}
+
+	for name, age := range ages {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	missing := filepath.Join(root, "missing")
+
+	fname := writePolicy(t, "policy.yaml", fmt.Sprintf(`policies:
+  - name: logs
+    roots: [%q]
+    ext: .log
+    older_than: 7d
+    actions: [archive, delete]
+    archive: %q
+  - name: tmp
+    roots: [%q, %q]
+    ext: .tmp
+    older_than: 90d
+    actions: [delete]
+`, root, archiveDir, root, missing))
+
+	policies, err := loadPolicies(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = runPolicies(policies, &buf, config{ordered: true})
+
+	// the missing root fails without stopping the other policies
+	if !errors.Is(err, ErrPartial) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected partial failure for the missing root, got %q", err)
+	}
+
+	expOut := []string{
+		fmt.Sprintf("POLICY logs: %s: 1 files, 4 bytes, ok", root),
+		fmt.Sprintf("POLICY tmp: %s: 1 files, 4 bytes, ok", root),
+		fmt.Sprintf("POLICY tmp: %s: 0 files, 0 bytes, failed", missing),
+	}
+	for _, exp := range expOut {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Expected output to contain %q, got %q", exp, buf.String())
+		}
+	}
+
+	for name, exists := range map[string]bool{"new.log": true, "old.log": false, "new.tmp": true, "old.tmp": false} {
+		if _, err := os.Stat(filepath.Join(root, name)); (err == nil) != exists {
+			t.Errorf("Expected %s to exist: %t, got %v", name, exists, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(archiveDir, "old.log.gz")); err != nil {
+		t.Errorf("Expected old.log archived: %s", err)
+	}
+}
+
+func TestRunPoliciesArchiveFileRoots(t *testing.T) {
+	roots := []string{t.TempDir(), t.TempDir()}
+	for i, root := range roots {
+		path := filepath.Join(root, fmt.Sprintf("file%d.log", i))
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiveFile := filepath.Join(t.TempDir(), "logs.tar.gz")
+
+	fname := writePolicy(t, "policy.yaml", fmt.Sprintf(`policies:
+  - name: logs
+    roots: [%q, %q]
+    actions: [archive, delete]
+    archive_file: %q
+`, roots[0], roots[1], archiveFile))
+
+	policies, err := loadPolicies(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := runPolicies(policies, &buf, config{ordered: true}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("Expected error %q, got %q instead", ErrInvalidPolicy, err)
+	}
+
+	// no root is processed, so nothing is deleted before being archived
+	for i, root := range roots {
+		if _, err := os.Stat(filepath.Join(root, fmt.Sprintf("file%d.log", i))); err != nil {
+			t.Errorf("Expected file in %s to exist: %s", root, err)
+		}
+	}
+
+	if _, err := os.Stat(archiveFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no archive, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// trashDateFormat is the DeletionDate layout required by the freedesktop
+// Trash specification, in local time
+const trashDateFormat = "2006-01-02T15:04:05"
+
+// trashCan moves files into a directory following the freedesktop Trash
+// layout: files are stored in files/ and described by a .trashinfo file
+// with the same name in info/, recording their original path and deletion
+// date. The same layout is used for quarantine directories, so trashed
+// files can be restored by any file manager
+type trashCan struct {
+	dir  string
+	home bool // the user trash, shared with other applications
+}
+
+// trashEntry represents a file stored in a trashCan
+type trashEntry struct {
+	name    string    // name inside files/ and info/
+	path    string    // original path
+	deleted time.Time // deletion date
+}
+
+// newTrashCan opens the trash can in dir, creating it if needed. If dir is
+// empty, the user's home trash is used
+func newTrashCan(dir string) (*trashCan, error) {
+	home := dir == ""
+
+	if home {
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		dir = filepath.Join(dataHome, "Trash")
+	}
+
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	return &trashCan{dir: dir, home: home}, nil
+}
+
+// put moves path into the trash can, returning where it was stored
+func (t *trashCan) put(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	// the info file is created exclusively first to reserve the name, as
+	// required by the specification
+	info, name, err := t.reserve(filepath.Base(abs))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: abs}).EscapedPath(), time.Now().Format(trashDateFormat))
+	if cerr := info.Close(); err == nil {
+		err = cerr
+	}
+
+	target := filepath.Join(t.dir, "files", name)
+
+	if err == nil {
+		err = moveFile(abs, target)
+	}
+
+	if err != nil {
+		os.Remove(t.infoPath(name))
+		return "", err
+	}
+
+	return target, nil
+}
+
+// reserve creates an empty info file for the first free name based on base
+func (t *trashCan) reserve(base string) (*os.File, string, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = stem + "." + strconv.Itoa(i) + ext
+		}
+
+		f, err := os.OpenFile(t.infoPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		// a file left behind without its info file also takes the name
+		if _, err := os.Lstat(filepath.Join(t.dir, "files", name)); err == nil {
+			f.Close()
+			os.Remove(f.Name())
+			continue
+		}
+
+		return f, name, nil
+	}
+}
+
+func (t *trashCan) infoPath(name string) string {
+	return filepath.Join(t.dir, "info", name+".trashinfo")
+}
+
+// expired returns the entries deleted more than retention before now,
+// sorted by deletion date. Info files that can't be parsed are skipped,
+// and returned as warnings
+func (t *trashCan) expired(retention time.Duration, now time.Time) ([]trashEntry, []error, error) {
+	infos, err := filepath.Glob(filepath.Join(t.dir, "info", "*.trashinfo"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		entries  []trashEntry
+		warnings []error
+	)
+
+	for _, fname := range infos {
+		e, err := readTrashInfo(fname)
+		if errors.Is(err, ErrInvalidTrashInfo) {
+			warnings = append(warnings, err)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if now.Sub(e.deleted) > retention {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].deleted.Before(entries[j].deleted)
+	})
+
+	return entries, warnings, nil
+}
+
+// remove permanently deletes e and its info file
+func (t *trashCan) remove(e trashEntry) error {
+	if err := os.RemoveAll(filepath.Join(t.dir, "files", e.name)); err != nil {
+		return err
+	}
+
+	return os.Remove(t.infoPath(e.name))
+}
+
+// readTrashInfo parses the .trashinfo file fname
+func readTrashInfo(fname string) (trashEntry, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return trashEntry{}, err
+	}
+	defer f.Close()
+
+	e := trashEntry{name: strings.TrimSuffix(filepath.Base(fname), ".trashinfo")}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "Path":
+			if e.path, err = url.PathUnescape(value); err != nil {
+				return trashEntry{}, fmt.Errorf("%w: %s: %s", ErrInvalidTrashInfo, fname, err)
+			}
+		case "DeletionDate":
+			if e.deleted, err = time.ParseInLocation(trashDateFormat, value, time.Local); err != nil {
+				return trashEntry{}, fmt.Errorf("%w: %s: %s", ErrInvalidTrashInfo, fname, err)
+			}
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return trashEntry{}, err
+	}
+
+	if e.path == "" || e.deleted.IsZero() {
+		return trashEntry{}, fmt.Errorf("%w: %s", ErrInvalidTrashInfo, fname)
+	}
+
+	return e, nil
+}
+
+// moveFile renames src to dst, copying it instead when they're on
+// different filesystems
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// setTrash configures c to move deleted files to the user trash or to the
+// quarantine directory, and parses the retention used by purge
+func (c *config) setTrash(trash bool, quarantine string, purge bool, retention string) error {
+	var err error
+
+	if c.retention, err = parseAge(retention); err != nil {
+		return err
+	}
+
+	c.purge = purge
+
+	if !trash && quarantine == "" {
+		return nil
+	}
+
+	c.trash, err = newTrashCan(quarantine)
+	return err
+}
+
+// purgeTrash permanently removes the files in cfg.trash deleted longer than
+// cfg.retention ago, or only lists them on a dry run. Only quarantine
+// directories are purged, since the user trash also holds the files other
+// applications deleted
+func purgeTrash(cfg config, out io.Writer, audit *auditLog) error {
+	if cfg.trash == nil || cfg.trash.home {
+		return fmt.Errorf("%w: -purge requires -quarantine", ErrInvalidTrash)
+	}
+
+	entries, warnings, err := cfg.trash.expired(cfg.retention, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(out, "SKIP %s\n", w)
+	}
+
+	for _, e := range entries {
+		if cfg.dryRun {
+			fmt.Fprintf(out, "PURGE %s\n", e.path)
+			continue
+		}
+
+		stored := filepath.Join(cfg.trash.dir, "files", e.name)
+		err := audit.track("purge", stored, func() (string, error) {
+			return "", cfg.trash.remove(e)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "PURGED %s\n", e.path)
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashPut(t *testing.T) {
+	trash, err := newTrashCan(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var stored []string
+
+	// the same name is trashed twice and must not collide
+	for _, sub := range []string{"a", "b c"} {
+		path := filepath.Join(dir, sub, "file.log")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(sub), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		target, err := trash.put(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, filepath.Base(target))
+
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s to be removed, got %v", path, err)
+		}
+
+		data, err := os.ReadFile(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != sub {
+			t.Errorf("Expected trashed content %q, got %q", sub, data)
+		}
+
+		e, err := readTrashInfo(trash.infoPath(filepath.Base(target)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.path != path {
+			t.Errorf("Expected original path %q, got %q", path, e.path)
+		}
+		if time.Since(e.deleted) > time.Minute {
+			t.Errorf("Expected recent deletion date, got %s", e.deleted)
+		}
+	}
+
+	if exp := "file.log,file.2.log"; strings.Join(stored, ",") != exp {
+		t.Errorf("Expected trashed names %q, got %q", exp, strings.Join(stored, ","))
+	}
+
+	info, err := os.ReadFile(trash.infoPath("file.2.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(info), "b%20c/file.log") {
+		t.Errorf("Expected escaped path in trash info, got %q", info)
+	}
+}
+
+func TestTrashHome(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	trash, err := newTrashCan("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := filepath.Join(dataHome, "Trash"); trash.dir != exp {
+		t.Errorf("Expected trash in %q, got %q", exp, trash.dir)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	testCases := []struct {
+		name      string
+		noTrash   bool
+		home      bool
+		malformed bool
+		dryRun    bool
+		expOut    string
+		expLeft   int
+		expErr    error
+	}{
+		{name: "Purge", expOut: "PURGED /data/old.log\n", expLeft: 1},
+		{name: "DryRun", dryRun: true, expOut: "PURGE /data/old.log\n", expLeft: 2},
+		{name: "Malformed", malformed: true,
+			expOut: "SKIP Invalid trash info: TRASH/info/bad.log.trashinfo\nPURGED /data/old.log\n", expLeft: 2},
+		{name: "NoTrash", noTrash: true, expErr: ErrInvalidTrash},
+		{name: "UserTrash", home: true, expErr: ErrInvalidTrash},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.home {
+				t.Setenv("XDG_DATA_HOME", dir)
+				dir = ""
+			}
+
+			trash, err := newTrashCan(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now()
+			for name, age := range map[string]time.Duration{"old.log": 40 * 24 * time.Hour, "new.log": time.Hour} {
+				info := fmt.Sprintf("[Trash Info]\nPath=/data/%s\nDeletionDate=%s\n",
+					name, now.Add(-age).Format(trashDateFormat))
+				if err := os.WriteFile(trash.infoPath(name), []byte(info), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(trash.dir, "files", name), nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// an info file without deletion date must not stop the purge
+			if tc.malformed {
+				if err := os.WriteFile(trash.infoPath("bad.log"), []byte("[Trash Info]\nPath=/data/bad.log\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(trash.dir, "files", "bad.log"), nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg := config{purge: true, dryRun: tc.dryRun, retention: 30 * 24 * time.Hour, trash: trash}
+			if tc.noTrash {
+				cfg.trash = nil
+			}
+
+			var buf bytes.Buffer
+			err = run(t.TempDir(), &buf, cfg)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("Expected error %q, got %q instead", tc.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out := strings.ReplaceAll(buf.String(), trash.dir, "TRASH"); out != tc.expOut {
+				t.Errorf("Expected %q, got %q instead", tc.expOut, out)
+			}
+
+			for _, sub := range []string{"files", "info"} {
+				left, err := os.ReadDir(filepath.Join(trash.dir, sub))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(left) != tc.expLeft {
+					t.Errorf("Expected %d entries left in %s, got %d", tc.expLeft, sub, len(left))
+				}
+			}
+		})
+	}
+}
+
+func TestReadTrashInfoInvalid(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "bad.trashinfo")
+	if err := os.WriteFile(fname, []byte("[Trash Info]\nPath=/data/bad\nDeletionDate=yesterday\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readTrashInfo(fname); !errors.Is(err, ErrInvalidTrashInfo) {
+		t.Errorf("Expected error %q, got %q instead", ErrInvalidTrashInfo, err)
+	}
+}
+
+func TestRunDelTrash(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 3, ".gz": 2})
+	defer cleanup()
+
+	trash, err := newTrashCan(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer, logBuffer bytes.Buffer
+	cfg := config{ext: ".log", del: true, trash: trash, wLog: &logBuffer}
+
+	if err := run(tempDir, &buffer, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	filesLeft, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filesLeft) != 2 {
+		t.Errorf("Expected 2 files left, got %d instead", len(filesLeft))
+	}
+
+	trashed, err := os.ReadDir(filepath.Join(trash.dir, "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 3 {
+		t.Errorf("Expected 3 files trashed, got %d instead", len(trashed))
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// entry represents a file found while traversing the tree, or the error
+// found reading it
+type entry struct {
+	path string
+	info fs.FileInfo
+	err  error
+}
+
+// walkFunc is called for every file, but not directory, found by walkTree
+type walkFunc func(path string, info fs.FileInfo) error
+
+// walkOptions controls how walkTree traverses the tree
+type walkOptions struct {
+	workers     int                    // directories read concurrently
+	ordered     bool                   // sort files before calling walkFunc
+	skipDir     func(path string) bool // directories not to descend into
+	followLinks bool                   // descend into linked directories and report link targets
+	xdev        bool                   // don't descend into directories on other filesystems
+	ignoreFile  string                 // name of the .gitignore style files honored in each directory
+	keepGoing   bool                   // record errors and keep walking
+}
+
+// walkErrors holds the errors recorded walking with keepGoing. It matches
+// ErrPartial and every error recorded
+type walkErrors struct {
+	errs []error
+}
+
+// record adds err, found processing path, to the list
+func (w *walkErrors) record(path string, err error) {
+	var pe *fs.PathError
+	if !errors.As(err, &pe) {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+
+	w.errs = append(w.errs, err)
+}
+
+// err returns w as an error, or nil if no error was recorded
+func (w *walkErrors) err() error {
+	if len(w.errs) == 0 {
+		return nil
+	}
+
+	return w
+}
+
+func (w *walkErrors) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: %d errors", ErrPartial, len(w.errs))
+	for _, err := range w.errs {
+		fmt.Fprintf(&b, "\n  %s", err)
+	}
+
+	return b.String()
+}
+
+func (w *walkErrors) Unwrap() []error {
+	return append([]error{ErrPartial}, w.errs...)
+}
+
+// dirTask is a directory waiting to be read
+type dirTask struct {
+	path   string
+	ignore *ignoreList // rules inherited from the parent directories
+}
+
+// walker holds the state shared by the goroutines reading directories
+type walker struct {
+	opts    walkOptions
+	rootDev uint64
+	hasDev  bool
+	found   chan<- dirTask
+	entries chan<- entry
+	stop    <-chan struct{}
+
+	mu      sync.Mutex
+	visited map[string]bool // real paths of directories read, to detect loops
+}
+
+// walkTree reads the directories under root concurrently, using up to
+// opts.workers goroutines, and calls fn for every file found. fn is never
+// called concurrently. If opts.ordered is set, files are sorted in the same
+// lexical order filepath.WalkDir uses before fn is called, which requires
+// keeping all of them in memory. With opts.followLinks, symbolic links are
+// reported as the files they point to and linked directories are read
+// once, so links pointing to their own parents don't loop forever. With
+// opts.keepGoing, errors reading the tree or returned by fn don't stop the
+// walk, and are returned together as a *walkErrors once it's complete
+func walkTree(root string, opts walkOptions, fn walkFunc) error {
+	stat := os.Lstat
+	if opts.followLinks {
+		stat = os.Stat
+	}
+
+	info, err := stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fn(root, info)
+	}
+
+	var errs walkErrors
+
+	visit := func(e entry) error {
+		err := e.err
+		if err == nil {
+			err = fn(e.path, e.info)
+		}
+
+		if err != nil && opts.keepGoing {
+			errs.record(e.path, err)
+			return nil
+		}
+
+		return err
+	}
+
+	workers := opts.workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		queue    = make(chan dirTask) // directories to read
+		found    = make(chan dirTask) // directories discovered by workers
+		dirDone  = make(chan struct{})
+		entries  = make(chan entry)
+		stop     = make(chan struct{})
+		stopOnce sync.Once
+		wg       sync.WaitGroup
+	)
+
+	w := &walker{
+		opts:    opts,
+		found:   found,
+		entries: entries,
+		stop:    stop,
+		visited: map[string]bool{},
+	}
+	w.rootDev, w.hasDev = fileDevice(info)
+	w.firstVisit(root)
+
+	// the dispatcher keeps an unbounded backlog of directories so workers
+	// never block each other while reporting the subdirectories they find
+	go func() {
+		defer close(queue)
+
+		backlog := []dirTask{{path: root}}
+		inFlight := 0
+
+		for len(backlog) > 0 || inFlight > 0 {
+			var (
+				next chan dirTask
+				head dirTask
+			)
+
+			if len(backlog) > 0 {
+				next = queue
+				head = backlog[len(backlog)-1]
+			}
+
+			select {
+			case next <- head:
+				backlog = backlog[:len(backlog)-1]
+				inFlight++
+			case dir := <-found:
+				backlog = append(backlog, dir)
+			case <-dirDone:
+				inFlight--
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for dir := range queue {
+				w.readDir(dir)
+
+				select {
+				case dirDone <- struct{}{}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(entries)
+	}()
+
+	// cancel stops the workers and waits for them to exit
+	cancel := func() {
+		stopOnce.Do(func() { close(stop) })
+		for range entries {
+		}
+	}
+
+	if !opts.ordered {
+		for e := range entries {
+			if err := visit(e); err != nil {
+				cancel()
+				return err
+			}
+		}
+
+		return errs.err()
+	}
+
+	var all []entry
+	for e := range entries {
+		all = append(all, e)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return lessPath(all[i].path, all[j].path)
+	})
+
+	for _, e := range all {
+		if err := visit(e); err != nil {
+			return err
+		}
+	}
+
+	return errs.err()
+}
+
+// readDir sends the files in dir to entries and its subdirectories, unless
+// skipped, to found
+func (w *walker) readDir(dir dirTask) {
+	send := func(e entry) bool {
+		select {
+		case w.entries <- e:
+			return true
+		case <-w.stop:
+			return false
+		}
+	}
+
+	ignore := dir.ignore
+	if w.opts.ignoreFile != "" {
+		var err error
+		if ignore, err = loadIgnore(dir.ignore, dir.path, w.opts.ignoreFile); err != nil {
+			send(entry{path: dir.path, err: err})
+			return
+		}
+	}
+
+	des, err := os.ReadDir(dir.path)
+	if err != nil {
+		send(entry{path: dir.path, err: err})
+		return
+	}
+
+	// entries are classified by their type, so the info is only read for
+	// the files reported and when needed to check directories
+	for _, d := range des {
+		path := filepath.Join(dir.path, d.Name())
+		isDir := d.IsDir()
+
+		// broken links are reported as links
+		var info fs.FileInfo
+		if w.opts.followLinks && d.Type()&fs.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil {
+				info, isDir = target, target.IsDir()
+			}
+		}
+
+		if ignore.match(path, isDir) {
+			continue
+		}
+
+		if info == nil && (!isDir || w.opts.xdev) {
+			var err error
+			if info, err = d.Info(); err != nil {
+				if !send(entry{path: path, err: err}) {
+					return
+				}
+				continue
+			}
+		}
+
+		if isDir {
+			if !w.descend(path, info) {
+				continue
+			}
+
+			select {
+			case w.found <- dirTask{path: path, ignore: ignore}:
+				continue
+			case <-w.stop:
+				return
+			}
+		}
+
+		if !send(entry{path: path, info: info}) {
+			return
+		}
+	}
+}
+
+// descend reports whether the directory path should be read. info is only
+// used, and required, to check the device with xdev
+func (w *walker) descend(path string, info fs.FileInfo) bool {
+	if w.opts.skipDir != nil && w.opts.skipDir(path) {
+		return false
+	}
+
+	if w.opts.xdev && w.hasDev {
+		if dev, ok := fileDevice(info); ok && dev != w.rootDev {
+			return false
+		}
+	}
+
+	return !w.opts.followLinks || w.firstVisit(path)
+}
+
+// firstVisit records the directory path, reporting whether it's the first
+// time it's found through any link. It's only needed when following links
+func (w *walker) firstVisit(path string) bool {
+	if !w.opts.followLinks {
+		return true
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		real = path
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.visited[real] {
+		return false
+	}
+
+	w.visited[real] = true
+	return true
+}
+
+// lessPath compares paths element by element, matching the order in
+// which filepath.WalkDir visits them
+func lessPath(a, b string) bool {
+	as := strings.Split(a, string(filepath.Separator))
+	bs := strings.Split(b, string(filepath.Separator))
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+
+	return len(as) < len(bs)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWalkTree(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, nil)
+	defer cleanup()
+
+	files := []string{
+		"a.txt",
+		"a/b.txt",
+		"a/c/d.txt",
+		"a.b/e.txt",
+		"z/y/x/w.txt",
+	}
+
+	var expected []string
+	for _, f := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(f))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		expected = append(expected, path)
+	}
+
+	// the same order filepath.WalkDir would produce
+	var walkOrder []string
+	filepath.WalkDir(tempDir, func(path string, d fs.DirEntry, err error) error {
+		if !d.IsDir() {
+			walkOrder = append(walkOrder, path)
+		}
+		return err
+	})
+
+	testCases := []struct {
+		name    string
+		workers int
+		ordered bool
+	}{
+		{name: "Ordered1Worker", workers: 1, ordered: true},
+		{name: "Ordered8Workers", workers: 8, ordered: true},
+		{name: "Unordered8Workers", workers: 8, ordered: false},
+		{name: "DefaultWorkers", workers: 0, ordered: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res []string
+
+			opts := walkOptions{workers: tc.workers, ordered: tc.ordered}
+
+			err := walkTree(tempDir, opts, func(path string, info fs.FileInfo) error {
+				res = append(res, path)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exp := walkOrder
+			if !tc.ordered {
+				exp = append([]string{}, expected...)
+				sort.Strings(exp)
+				sort.Strings(res)
+			}
+
+			if len(res) != len(exp) {
+				t.Fatalf("Expected %q, got %q instead", exp, res)
+			}
+
+			for i := range exp {
+				if res[i] != exp[i] {
+					t.Errorf("Expected %q, got %q instead", exp, res)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWalkTreeError(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 50})
+	defer cleanup()
+
+	errStop := errors.New("stop")
+	calls := 0
+
+	err := walkTree(tempDir, walkOptions{workers: 4}, func(path string, info fs.FileInfo) error {
+		calls++
+		return errStop
+	})
+
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected error %q, got %q instead", errStop, err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected walk to stop after 1 call, got %d calls", calls)
+	}
+
+	if err := walkTree(filepath.Join(tempDir, "missing"), walkOptions{workers: 4}, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected error %q, got %q instead", os.ErrNotExist, err)
+	}
+}
+
+func TestWalkTreeFileRoot(t *testing.T) {
+	var res []string
+
+	err := walkTree("testdata/dir.log", walkOptions{workers: 2}, func(path string, info fs.FileInfo) error {
+		res = append(res, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 1 || res[0] != "testdata/dir.log" {
+		t.Errorf("Expected [testdata/dir.log], got %q instead", res)
+	}
+}
+
+func TestWalkTreeOptions(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, nil)
+	defer cleanup()
+
+	for _, f := range []string{"a/file.txt", "a/skip.tmp", "b/other.txt"} {
+		path := filepath.Join(tempDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, ".walkignore"), []byte("*.tmp\nb/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a link to a file, a link to a parent directory and a broken link
+	links := map[string]string{
+		"link.txt":   filepath.Join("a", "file.txt"),
+		"a/loop":     "..",
+		"broken.txt": "missing",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(tempDir, filepath.FromSlash(name))); err != nil {
+			t.Skip("Symbolic links not supported:", err)
+		}
+	}
+
+	testCases := []struct {
+		name string
+		opts walkOptions
+		exp  []string
+	}{
+		{name: "Default", opts: walkOptions{},
+			exp: []string{".walkignore", "a/file.txt", "a/loop", "a/skip.tmp", "b/other.txt", "broken.txt", "link.txt"}},
+		{name: "FollowLinks", opts: walkOptions{followLinks: true},
+			exp: []string{".walkignore", "a/file.txt", "a/skip.tmp", "b/other.txt", "broken.txt", "link.txt"}},
+		{name: "IgnoreFile", opts: walkOptions{ignoreFile: ".walkignore"},
+			exp: []string{".walkignore", "a/file.txt", "a/loop", "broken.txt", "link.txt"}},
+		{name: "Xdev", opts: walkOptions{xdev: true},
+			exp: []string{".walkignore", "a/file.txt", "a/loop", "a/skip.tmp", "b/other.txt", "broken.txt", "link.txt"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res []string
+			modes := map[string]fs.FileMode{}
+
+			tc.opts.workers = 4
+			tc.opts.ordered = true
+
+			err := walkTree(tempDir, tc.opts, func(path string, info fs.FileInfo) error {
+				rel, err := filepath.Rel(tempDir, path)
+				if err != nil {
+					return err
+				}
+				res = append(res, filepath.ToSlash(rel))
+				modes[filepath.ToSlash(rel)] = info.Mode()
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != len(tc.exp) {
+				t.Fatalf("Expected %q, got %q instead", tc.exp, res)
+			}
+
+			for i := range tc.exp {
+				if res[i] != tc.exp[i] {
+					t.Errorf("Expected %q, got %q instead", tc.exp, res)
+					break
+				}
+			}
+
+			linked := modes["link.txt"]&fs.ModeSymlink != 0
+			if linked == tc.opts.followLinks {
+				t.Errorf("Expected link.txt followed: %t, got mode %s", tc.opts.followLinks, modes["link.txt"])
+			}
+		})
+	}
+}
+
+func TestWalkTreeKeepGoing(t *testing.T) {
+	tempDir, cleanup := createTempDir(t, map[string]int{".log": 10})
+	defer cleanup()
+
+	errFile := errors.New("file error")
+	calls := 0
+
+	opts := walkOptions{workers: 4, keepGoing: true}
+
+	err := walkTree(tempDir, opts, func(path string, info fs.FileInfo) error {
+		calls++
+		if calls%2 == 0 {
+			return errFile
+		}
+		return nil
+	})
+
+	if calls != 10 {
+		t.Errorf("Expected 10 calls, got %d", calls)
+	}
+
+	if !errors.Is(err, ErrPartial) || !errors.Is(err, errFile) {
+		t.Fatalf("Expected errors %q and %q, got %q instead", ErrPartial, errFile, err)
+	}
+
+	var werr *walkErrors
+	if !errors.As(err, &werr) || len(werr.errs) != 5 {
+		t.Fatalf("Expected 5 errors recorded, got %v", err)
+	}
+
+	if !strings.HasPrefix(werr.errs[0].Error(), tempDir) {
+		t.Errorf("Expected error to name the file, got %q", werr.errs[0])
+	}
+
+	if err := walkTree(tempDir, opts, func(string, fs.FileInfo) error { return nil }); err != nil {
+		t.Errorf("Expected no error, got %q", err)
+	}
+}